@@ -3,13 +3,31 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/ap/netstorage"
 	"github.com/ap/pulp"
 )
 
 func main() {
-	ns := netstorage.NewClient("host", "base-folder", "keyname", "value")
+	// credentials come from the [netstorage] section of ~/.edgerc, or the
+	// file named by NETSTORAGE_CONFIG, and NETSTORAGE_* variables
+	config := os.Getenv("NETSTORAGE_CONFIG")
+	if config == "" {
+		home, _ := os.UserHomeDir()
+		config = filepath.Join(home, ".edgerc")
+	}
+	cfg, err := netstorage.LoadConfig(config)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ns, err := cfg.Client("netstorage")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
 	fmt.Println("===========================")
 	fmt.Println("Du")
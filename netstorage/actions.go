@@ -0,0 +1,97 @@
+// netstorage project actions.go
+package netstorage
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListOptions are the optional parameters of the ObjectStore list action.
+type ListOptions struct {
+	// Start is a resume marker returned by an earlier List. When set it is
+	// used as the path to list from instead of the one given to List.
+	Start string
+	// End stops the listing at this path (not relative to the client's
+	// folder, like Start).
+	End string
+	// MaxEntries limits the number of entries returned. Zero lets the
+	// server decide.
+	MaxEntries int
+}
+
+// A ListResult is one page of a recursive ObjectStore listing.
+type ListResult struct {
+	Files  []NSFile `xml:"file" json:"file"`
+	Resume Resume   `xml:"resume" json:"resume"`
+}
+
+// More reports whether more entries are available past this page.
+func (l *ListResult) More() bool {
+	return l.Resume.Start != ""
+}
+
+// Next returns the options that fetch the page following l.
+func (l *ListResult) Next(opts *ListOptions) *ListOptions {
+	next := ListOptions{}
+	if opts != nil {
+		next = *opts
+	}
+	next.Start = l.Resume.Start
+	return &next
+}
+
+// Creates a symbolic link at name pointing to target. Both paths are relative
+// to the client's folder.
+func (client *NetstorageClient) Symlink(target, name string) error {
+	return client.SymlinkContext(context.Background(), target, name)
+}
+
+// SymlinkContext is like Symlink but uses ctx for the request.
+func (client *NetstorageClient) SymlinkContext(ctx context.Context, target, name string) error {
+	filename := path.Join(client.Folder, name)
+	action := "symlink&target=" + url.QueryEscape("/"+path.Join(client.Folder, target))
+	return client.execute(ctx, "PUT", filename, action, nil)
+}
+
+// Sets the modification time of a file or symlink.
+func (client *NetstorageClient) SetMtime(file string, mtime time.Time) error {
+	return client.SetMtimeContext(context.Background(), file, mtime)
+}
+
+// SetMtimeContext is like SetMtime but uses ctx for the request.
+func (client *NetstorageClient) SetMtimeContext(ctx context.Context, file string, mtime time.Time) error {
+	filename := path.Join(client.Folder, file)
+	action := "mtime&mtime=" + strconv.FormatInt(mtime.Unix(), 10)
+	return client.execute(ctx, "PUT", filename, action, nil)
+}
+
+// Lists the files below a directory of an ObjectStore account recursively, in
+// lexical order. A result with More set is a partial page; pass
+// ListResult.Next to fetch the following one.
+func (client *NetstorageClient) List(filepath string, opts *ListOptions) (ListResult, error) {
+	return client.ListContext(context.Background(), filepath, opts)
+}
+
+// ListContext is like List but uses ctx for the request.
+func (client *NetstorageClient) ListContext(ctx context.Context, filepath string, opts *ListOptions) (ListResult, error) {
+	var list ListResult
+	filename := path.Join(client.Folder, filepath)
+	params := url.Values{}
+	if opts != nil {
+		if opts.Start != "" {
+			filename = strings.TrimPrefix(path.Clean(opts.Start), "/")
+		}
+		if opts.End != "" {
+			params.Set("end", opts.End)
+		}
+		if opts.MaxEntries > 0 {
+			params.Set("max_entries", strconv.Itoa(opts.MaxEntries))
+		}
+	}
+	err := client.listing(ctx, filename, "list", params, &list)
+	return list, err
+}
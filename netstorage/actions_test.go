@@ -0,0 +1,142 @@
+// netstorage project actions_test.go
+package netstorage
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSymlink(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/link",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkMethod(t, r, "PUT")
+			checkAction(t, r, "version=1&action=symlink&target=%2Fbase%2Fdir%2Ffile.txt")
+		},
+	)
+	if err := client.Symlink("dir/file.txt", "link"); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
+
+func TestSetMtime(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkMethod(t, r, "PUT")
+			checkAction(t, r, "version=1&action=mtime&mtime=1136214245")
+		},
+	)
+	if err := client.SetMtime("file.txt", time.Unix(1136214245, 0)); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/dir",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkMethod(t, r, "GET")
+			checkAction(t, r, "version=1&action=list&format=xml&max_entries=1")
+			fmt.Fprint(w, `<list><file type="file" name="base/dir/a" size="1" md5="x" mtime="2"/><resume start="/base/dir/a"/></list>`)
+		},
+	)
+	mux.HandleFunc("/base/dir/a",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkAction(t, r, "version=1&action=list&format=xml&max_entries=1")
+			fmt.Fprint(w, `<list><file type="file" name="base/dir/b" size="3" mtime="4"/></list>`)
+		},
+	)
+
+	opts := &ListOptions{MaxEntries: 1}
+	page, err := client.List("dir", opts)
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	expected := []NSFile{{Type: "file", Name: "base/dir/a", Size: 1, Md5: "x", Mtime: 2}}
+	if !reflect.DeepEqual(page.Files, expected) || !page.More() {
+		t.Errorf("got %#v expected %#v with more pages", page, expected)
+	}
+	page, err = client.List("dir", page.Next(opts))
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	expected = []NSFile{{Type: "file", Name: "base/dir/b", Size: 3, Mtime: 4}}
+	if !reflect.DeepEqual(page.Files, expected) || page.More() {
+		t.Errorf("got %#v expected %#v as the last page", page, expected)
+	}
+}
+
+func TestRename(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/old.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkMethod(t, r, "POST")
+			checkAction(t, r, "version=1&action=rename&destination=%2Fbase%2Fnew.txt")
+		},
+	)
+	if err := client.Rename("old.txt", "new.txt"); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
+
+func TestMove(t *testing.T) {
+	var madeDir bool
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/a/b",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkAction(t, r, "version=1&action=mkdir")
+			madeDir = true
+		},
+	)
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			if !madeDir {
+				t.Errorf("renamed before creating the target directory")
+			}
+			checkAction(t, r, "version=1&action=rename&destination=%2Fbase%2Fa%2Fb%2Ffile.txt")
+		},
+	)
+	if err := client.Move("file.txt", "a/b/file.txt"); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/src.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkAction(t, r, "version=1&action=download")
+			w.Header().Set("ETag", `"5d41402abc4b2a76b9719d911017c592"`)
+			fmt.Fprint(w, "hello")
+		},
+	)
+	mux.HandleFunc("/base/dst.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkMethod(t, r, "PUT")
+			checkAction(t, r, "version=1&action=upload&md5=5d41402abc4b2a76b9719d911017c592&size=5")
+			if data, _ := io.ReadAll(r.Body); string(data) != "hello" {
+				t.Errorf("body: got %q", data)
+			}
+		},
+	)
+	if err := client.Copy("src.txt", "dst.txt"); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
@@ -0,0 +1,32 @@
+// netstorage project api.go
+package netstorage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// NetstorageAPI is the set of NetStorage operations a NetstorageClient
+// performs. Depend on it instead of *NetstorageClient to be able to substitute
+// a mock, or to wrap a client with the Decorate functions. The convenience
+// methods of NetstorageClient (Upload, Move, Walk, Sync and so on) are built
+// on these operations.
+type NetstorageAPI interface {
+	UploadWithOptions(ctx context.Context, name string, r io.Reader, opts *UploadOptions) error
+	DownloadStream(ctx context.Context, file string) (io.ReadCloser, *DownloadInfo, error)
+	DownloadRange(ctx context.Context, file string, offset, length int64) (io.ReadCloser, *DownloadInfo, error)
+	MakeDirContext(ctx context.Context, dirname string) error
+	RemoveDirContext(ctx context.Context, dirname string) error
+	DeleteContext(ctx context.Context, file string) error
+	QuickDeleteContext(ctx context.Context, dirname string) error
+	RenameContext(ctx context.Context, file string, newname string) error
+	SymlinkContext(ctx context.Context, target, name string) error
+	SetMtimeContext(ctx context.Context, file string, mtime time.Time) error
+	DirWithOptions(ctx context.Context, filepath string, opts *DirOptions) (Stat, error)
+	ListContext(ctx context.Context, filepath string, opts *ListOptions) (ListResult, error)
+	DiskUsageContext(ctx context.Context, filepath string) (Du, error)
+	StatisticsContext(ctx context.Context, filepath string) (Stat, error)
+}
+
+var _ NetstorageAPI = (*NetstorageClient)(nil)
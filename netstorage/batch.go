@@ -0,0 +1,175 @@
+// netstorage project batch.go
+package netstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// ErrSkipped is the error of batch operations that were not started because
+// an earlier one failed with BatchOptions.StopOnError set, or because the
+// context was done.
+var ErrSkipped = errors.New("netstorage: skipped")
+
+// A BatchAction is what a BatchOp does.
+type BatchAction string
+
+const (
+	BatchUpload BatchAction = "upload"
+	BatchDelete BatchAction = "delete"
+)
+
+// A BatchOp is a single operation of a Batch.
+type BatchOp struct {
+	Action BatchAction
+	Path   string // remote path relative to the client's folder
+
+	// Uploads read their data from LocalPath, or else from Data. A local
+	// file is sent with its size, modification time and a content type
+	// derived from its extension unless Options is set.
+	LocalPath string
+	Data      io.Reader
+	Options   *UploadOptions
+}
+
+// BatchOptions controls how Batch runs its operations.
+type BatchOptions struct {
+	// Concurrency is the number of operations run at the same time.
+	Concurrency int
+	// StopOnError stops starting operations once one has failed. Operations
+	// already running are completed. By default all operations are tried.
+	StopOnError bool
+}
+
+// A BatchResult is the outcome of a BatchOp.
+type BatchResult struct {
+	Op       BatchOp
+	Err      error // ErrSkipped if the operation was not started
+	Duration time.Duration
+}
+
+// Batch runs ops with a bounded number of workers and returns their results
+// in the order of ops. The parent directory of every upload is created with
+// MakeDir first, once per directory. The returned error joins the errors of
+// all failed operations, or is nil if all succeeded.
+func (client *NetstorageClient) Batch(ctx context.Context, ops []BatchOp, opts *BatchOptions) ([]BatchResult, error) {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	results := make([]BatchResult, len(ops))
+	dirs := &batchDirs{client: client, made: make(map[string]*batchDir)}
+
+	var wg sync.WaitGroup
+	var failed sync.Once
+	stop := make(chan struct{})
+	sem := make(chan struct{}, max(opts.Concurrency, 1))
+	for i, op := range ops {
+		result := &results[i]
+		result.Op = op
+		result.Err = ErrSkipped
+		select {
+		case sem <- struct{}{}:
+		case <-stop:
+			continue
+		case <-ctx.Done():
+			continue
+		}
+		select {
+		case <-stop:
+			<-sem
+			continue
+		case <-ctx.Done():
+			<-sem
+			continue
+		default:
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			start := time.Now()
+			err := client.batchOp(ctx, result.Op, dirs)
+			result.Err, result.Duration = err, time.Since(start)
+			if err != nil && opts.StopOnError {
+				failed.Do(func() { close(stop) })
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	skipped := false
+	for _, result := range results {
+		if result.Err == ErrSkipped {
+			skipped = true
+		} else if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", result.Op.Action, result.Op.Path, result.Err))
+		}
+	}
+	if len(errs) == 0 && skipped {
+		return results, ctx.Err()
+	}
+	return results, errors.Join(errs...)
+}
+
+func (client *NetstorageClient) batchOp(ctx context.Context, op BatchOp, dirs *batchDirs) error {
+	switch op.Action {
+	case BatchDelete:
+		return client.DeleteContext(ctx, op.Path)
+	case BatchUpload:
+		if err := dirs.make(ctx, path.Dir(path.Clean("/"+op.Path))); err != nil {
+			return err
+		}
+		switch {
+		case op.LocalPath != "" && op.Options == nil:
+			return client.UploadFile(ctx, op.LocalPath, op.Path)
+		case op.LocalPath != "":
+			f, err := os.Open(op.LocalPath)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return client.UploadWithOptions(ctx, op.Path, f, op.Options)
+		case op.Data != nil:
+			return client.UploadWithOptions(ctx, op.Path, op.Data, op.Options)
+		}
+		return errors.New("netstorage: batch upload without data")
+	}
+	return fmt.Errorf("netstorage: unknown batch action %q", op.Action)
+}
+
+// batchDirs creates each directory of a batch once, even when several
+// workers need it at the same time.
+type batchDirs struct {
+	client *NetstorageClient
+	mu     sync.Mutex
+	made   map[string]*batchDir
+}
+
+type batchDir struct {
+	once sync.Once
+	err  error
+}
+
+func (d *batchDirs) make(ctx context.Context, dir string) error {
+	if dir == "/" {
+		return nil
+	}
+	d.mu.Lock()
+	entry, ok := d.made[dir]
+	if !ok {
+		entry = &batchDir{}
+		d.made[dir] = entry
+	}
+	d.mu.Unlock()
+	entry.once.Do(func() {
+		entry.err = d.client.MakeDirContext(ctx, dir)
+	})
+	return entry.err
+}
@@ -0,0 +1,96 @@
+// netstorage project batch_test.go
+package netstorage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/",
+		func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests = append(requests, requestAction(r)+" "+r.URL.Path)
+			mu.Unlock()
+			if r.URL.Path == "/base/missing" {
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		},
+	)
+	local := filepath.Join(t.TempDir(), "style.css")
+	os.WriteFile(local, []byte("body {}"), 0644)
+	ops := []BatchOp{
+		{Action: BatchUpload, Path: "site/index.html", Data: strings.NewReader("<html>")},
+		{Action: BatchUpload, Path: "site/css/style.css", LocalPath: local},
+		{Action: BatchUpload, Path: "site/about.html", Data: strings.NewReader("<html>")},
+		{Action: BatchDelete, Path: "missing"},
+		{Action: BatchDelete, Path: "old.html"},
+	}
+	results, err := client.Batch(context.Background(), ops, &BatchOptions{Concurrency: 3})
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "delete missing") {
+		t.Errorf("expected the delete of missing to fail, got %v", err)
+	}
+	for i, result := range results {
+		if result.Op.Path != ops[i].Path {
+			t.Errorf("result %d is for %s", i, result.Op.Path)
+		}
+		if (result.Err != nil) != (ops[i].Path == "missing") {
+			t.Errorf("%s: unexpected error %v", ops[i].Path, result.Err)
+		}
+	}
+	sort.Strings(requests)
+	expected := []string{
+		"delete /base/missing",
+		"delete /base/old.html",
+		"mkdir /base/site",
+		"mkdir /base/site/css",
+		"upload /base/site/about.html",
+		"upload /base/site/css/style.css",
+		"upload /base/site/index.html",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got requests\n%s\nexpected\n%s", strings.Join(requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestBatchStopOnError(t *testing.T) {
+	var deleted []string
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/",
+		func(w http.ResponseWriter, r *http.Request) {
+			deleted = append(deleted, r.URL.Path)
+			if r.URL.Path == "/base/b" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+			}
+		},
+	)
+	ops := []BatchOp{
+		{Action: BatchDelete, Path: "a"},
+		{Action: BatchDelete, Path: "b"},
+		{Action: BatchDelete, Path: "c"},
+	}
+	results, err := client.Batch(context.Background(), ops, &BatchOptions{StopOnError: true})
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+	if results[0].Err != nil || !errors.Is(results[1].Err, ErrForbidden) || results[2].Err != ErrSkipped {
+		t.Errorf("unexpected results %+v", results)
+	}
+	if strings.Join(deleted, ",") != "/base/a,/base/b" {
+		t.Errorf("deleted %v", deleted)
+	}
+}
@@ -0,0 +1,363 @@
+// netstorage project cache.go
+package netstorage
+
+import (
+	"container/list"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOptions controls a Cache.
+type CacheOptions struct {
+	// MaxBytes is the size the cached files may take up. The least
+	// recently used files are evicted to stay below it, and larger files
+	// are not cached at all. Zero means no limit.
+	MaxBytes int64
+	// Verify checks the md5 of a cached file against the one NetStorage
+	// reports before serving it, rather than only its size.
+	Verify bool
+}
+
+// A Cache keeps downloaded files in a local directory. Files are stored by
+// their md5, so identical content downloaded from different paths is kept
+// once; files NetStorage reports no md5 for are stored by their path,
+// modification time and size. A Cache is safe for concurrent use and may be
+// shared by several clients.
+type Cache struct {
+	dir  string
+	opts CacheOptions
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // of *cacheEntry, by key
+	lru     *list.List               // most recently used first
+	size    int64
+	stats   CacheStats
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// CacheStats are the counters of a Cache.
+type CacheStats struct {
+	Hits      int64 // downloads served from the cache
+	Misses    int64 // downloads that went to NetStorage
+	Evictions int64 // files removed to make room
+	Entries   int   // files cached
+	Bytes     int64 // size of the files cached
+}
+
+// NewCache returns a cache storing its files in dir, which is created if
+// needed. Files left in dir by an earlier Cache are reused, the least
+// recently modified being the first to be evicted.
+func NewCache(dir string, opts *CacheOptions) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c := &Cache{dir: dir, entries: make(map[string]*list.Element), lru: list.New()}
+	if opts != nil {
+		c.opts = *opts
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type found struct {
+		key   string
+		size  int64
+		mtime time.Time
+	}
+	var files []found
+	for _, d := range dirEntries {
+		name := d.Name()
+		if strings.HasPrefix(name, ".tmp-") {
+			// left over from an interrupted download
+			os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		if !d.Type().IsRegular() || !isCacheKey(name) {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, found{name, info.Size(), info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mtime.After(files[j].mtime) })
+	for _, f := range files {
+		c.entries[f.key] = c.lru.PushBack(&cacheEntry{key: f.key, size: f.size})
+		c.size += f.size
+	}
+	c.evict("")
+	return c, nil
+}
+
+// Stats returns a snapshot of the cache's counters.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.size
+	return stats
+}
+
+// cacheKey returns the key a remote file is cached under.
+func cacheKey(name string, file NSFile) string {
+	if isHexMd5(file.Md5) {
+		return strings.ToLower(file.Md5)
+	}
+	sum := sha256.Sum256([]byte(name + "\x00" + strconv.FormatInt(file.Mtime, 10) + "\x00" + strconv.FormatInt(file.Size, 10)))
+	return "k-" + hex.EncodeToString(sum[:])
+}
+
+func isCacheKey(name string) bool {
+	if rest, ok := strings.CutPrefix(name, "k-"); ok {
+		_, err := hex.DecodeString(rest)
+		return err == nil && len(rest) == 2*sha256.Size
+	}
+	return isHexMd5(name) && name == strings.ToLower(name)
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+// open returns the cached file for key if it is present and valid for file.
+func (c *Cache) open(key string, file NSFile) (*os.File, bool) {
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	f, err := os.Open(c.path(key))
+	if err == nil {
+		if err = c.validate(f, key, file); err != nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		c.remove(key)
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(c.path(key), now, now)
+	return f, true
+}
+
+// validate checks a cached file against the remote one.
+func (c *Cache) validate(f *os.File, key string, file NSFile) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != file.Size {
+		return fmt.Errorf("netstorage: cached %s has %d bytes, want %d", key, info.Size(), file.Size)
+	}
+	if !c.opts.Verify || strings.HasPrefix(key, "k-") {
+		return nil
+	}
+	hash := md5.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != key {
+		return fmt.Errorf("netstorage: cached %s has md5 %s", key, sum)
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
+
+// store copies body, the size bytes of a remote file, into the cache under
+// key and returns the cached file opened for reading and true. If body is
+// not that version of the file, e.g. because the file changed after it was
+// looked up, its data is returned uncached in a temporary file that is
+// removed on Close, and false.
+func (c *Cache) store(key string, size int64, body io.Reader) (io.ReadCloser, bool, error) {
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return nil, false, err
+	}
+	hash := md5.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), body)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, false, err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if written != size || (!strings.HasPrefix(key, "k-") && sum != key) {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return nil, false, err
+		}
+		return uncachedFile{tmp}, false, nil
+	}
+	err = tmp.Close()
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, false, err
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		// stored concurrently by another download
+		c.size -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, size: written})
+	c.size += written
+	c.mu.Unlock()
+	c.evict(key)
+	cached, err := os.Open(c.path(key))
+	if err != nil {
+		return nil, false, err
+	}
+	return cached, true, nil
+}
+
+// uncachedFile is downloaded data that is not kept in the cache.
+type uncachedFile struct {
+	*os.File
+}
+
+func (f uncachedFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// remove drops key from the cache.
+func (c *Cache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.size -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+	os.Remove(c.path(key))
+}
+
+// evict removes the least recently used files until the cache fits in
+// MaxBytes, keeping the file stored under keep.
+func (c *Cache) evict(keep string) {
+	if c.opts.MaxBytes <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for elem := c.lru.Back(); elem != nil && c.size > c.opts.MaxBytes; {
+		prev := elem.Prev()
+		entry := elem.Value.(*cacheEntry)
+		if entry.key != keep {
+			c.lru.Remove(elem)
+			delete(c.entries, entry.key)
+			c.size -= entry.size
+			c.stats.Evictions++
+			os.Remove(c.path(entry.key))
+		}
+		elem = prev
+	}
+}
+
+func (c *Cache) count(hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hit {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+}
+
+// WithCache makes DownloadCached keep downloaded files in c.
+func WithCache(c *Cache) Option {
+	return func(client *NetstorageClient) {
+		client.cache = c
+	}
+}
+
+// WithReadThroughCache is like WithCache, and makes Download and
+// DownloadContext go through DownloadCached as well.
+func WithReadThroughCache(c *Cache) Option {
+	return func(client *NetstorageClient) {
+		client.cache = c
+		client.readThrough = true
+	}
+}
+
+// DownloadCached downloads a file through the client's cache. The md5,
+// modification time and size of the file are looked up with Statistics, and
+// the file is only downloaded if the cache holds no valid copy of that
+// version. If the file changes between the lookup and the download, the new
+// version is returned without being cached. Without a cache it is like
+// DownloadStream. The caller must close the returned reader.
+func (client *NetstorageClient) DownloadCached(ctx context.Context, file string) (io.ReadCloser, *DownloadInfo, error) {
+	c := client.cache
+	if c == nil {
+		return client.DownloadStream(ctx, file)
+	}
+	stat, err := client.StatisticsContext(ctx, file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(stat.Files) != 1 || stat.Files[0].Type != "file" {
+		return client.DownloadStream(ctx, file)
+	}
+	nsfile := stat.Files[0]
+	info := &DownloadInfo{
+		ContentLength: nsfile.Size,
+		LastModified:  nsfile.ModTime(),
+		Md5:           strings.ToLower(nsfile.Md5),
+	}
+	key := cacheKey(path.Join(client.Folder, file), nsfile)
+	if f, ok := c.open(key, nsfile); ok {
+		c.count(true)
+		return f, info, nil
+	}
+	c.count(false)
+
+	body, dlInfo, err := client.DownloadStream(ctx, file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.opts.MaxBytes > 0 && nsfile.Size > c.opts.MaxBytes {
+		return body, dlInfo, nil
+	}
+	defer body.Close()
+	f, stored, err := c.store(key, nsfile.Size, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stored {
+		dlInfo.ContentLength = nsfile.Size
+		if dlInfo.Md5 == "" {
+			dlInfo.Md5 = info.Md5
+		}
+	}
+	return f, dlInfo, nil
+}
@@ -0,0 +1,192 @@
+// netstorage project cache_test.go
+package netstorage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// handleCached serves files for stat and download, counting downloads.
+func handleCached(t *testing.T, files map[string]string, downloads map[string]int) {
+	mux.HandleFunc("/base/",
+		func(w http.ResponseWriter, r *http.Request) {
+			name := strings.TrimPrefix(r.URL.Path, "/base/")
+			data, ok := files[name]
+			if !ok {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			switch requestAction(r) {
+			case "stat":
+				sum := md5.Sum([]byte(data))
+				fmt.Fprintf(w, `<stat directory="/base"><file type="file" name="%s" size="%d" md5="%s" mtime="1136214245"/></stat>`,
+					name, len(data), hex.EncodeToString(sum[:]))
+			case "download":
+				downloads[name]++
+				io.WriteString(w, data)
+			default:
+				t.Errorf("unexpected action %s", requestAction(r))
+			}
+		},
+	)
+}
+
+func readCached(t *testing.T, name string) string {
+	t.Helper()
+	body, _, err := client.DownloadCached(context.Background(), name)
+	if err != nil {
+		t.Fatalf("download %s: %s", name, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read %s: %s", name, err)
+	}
+	return string(data)
+}
+
+func TestDownloadCached(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), &CacheOptions{Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup(WithCache(cache))
+	defer teardown()
+
+	files := map[string]string{"a.txt": "hello", "copy.txt": "hello"}
+	downloads := map[string]int{}
+	handleCached(t, files, downloads)
+
+	for i := 0; i < 2; i++ {
+		if got := readCached(t, "a.txt"); got != "hello" {
+			t.Errorf("got %q", got)
+		}
+	}
+	// same content under another path is served from the cache as well
+	if got := readCached(t, "copy.txt"); got != "hello" {
+		t.Errorf("got %q", got)
+	}
+	if downloads["a.txt"] != 1 || downloads["copy.txt"] != 0 {
+		t.Errorf("downloads %v", downloads)
+	}
+	// a changed file is downloaded again
+	files["a.txt"] = "changed"
+	if got := readCached(t, "a.txt"); got != "changed" || downloads["a.txt"] != 2 {
+		t.Errorf("got %q after %d downloads", got, downloads["a.txt"])
+	}
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Entries != 2 || stats.Bytes != 12 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCacheChangedDuringDownload(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewCache(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup(WithReadThroughCache(cache))
+	defer teardown()
+
+	// the file is replaced after it was looked up
+	mux.HandleFunc("/base/b.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			if requestAction(r) == "stat" {
+				fmt.Fprint(w, `<stat directory="/base"><file type="file" name="b.txt" size="5" md5="5d41402abc4b2a76b9719d911017c592" mtime="1136214245"/></stat>`)
+				return
+			}
+			io.WriteString(w, "replaced")
+		},
+	)
+
+	body, err := client.Download("b.txt")
+	if err != nil {
+		t.Fatalf("download: %s", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "replaced" {
+		t.Errorf("got %q", data)
+	}
+	if stats := cache.Stats(); stats.Entries != 0 {
+		t.Errorf("changed file was cached: %+v", stats)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("left %d files behind", len(entries))
+	}
+}
+
+func TestCacheValidation(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewCache(dir, &CacheOptions{Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup(WithCache(cache))
+	defer teardown()
+
+	files := map[string]string{"a.txt": "hello"}
+	downloads := map[string]int{}
+	handleCached(t, files, downloads)
+
+	readCached(t, "a.txt")
+	// corrupt the cached copy without changing its size
+	os.WriteFile(filepath.Join(dir, "5d41402abc4b2a76b9719d911017c592"), []byte("jello"), 0644)
+	if got := readCached(t, "a.txt"); got != "hello" || downloads["a.txt"] != 2 {
+		t.Errorf("got %q after %d downloads", got, downloads["a.txt"])
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewCache(dir, &CacheOptions{MaxBytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup(WithReadThroughCache(cache))
+	defer teardown()
+
+	files := map[string]string{"a": "aaaa", "b": "bbbb", "c": "cccc", "big": "0123456789abc"}
+	downloads := map[string]int{}
+	handleCached(t, files, downloads)
+
+	for _, name := range []string{"a", "b", "a", "c", "a", "b", "big"} {
+		body, err := client.Download(name)
+		if err != nil {
+			t.Fatalf("download %s: %s", name, err)
+		}
+		if data, _ := io.ReadAll(body); string(data) != files[name] {
+			t.Errorf("%s: got %q", name, data)
+		}
+		body.Close()
+	}
+	// b was evicted for c, being used less recently than a
+	expected := map[string]int{"a": 1, "b": 2, "c": 1, "big": 1}
+	for name, n := range expected {
+		if downloads[name] != n {
+			t.Errorf("%s downloaded %d times, expected %d", name, downloads[name], n)
+		}
+	}
+	stats := cache.Stats()
+	if stats.Bytes > 10 || stats.Evictions != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// a new cache picks up the files left behind
+	reopened, err := NewCache(dir, &CacheOptions{MaxBytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Stats(); got.Entries != 2 || got.Bytes != 8 {
+		t.Errorf("reopened cache: %+v", got)
+	}
+}
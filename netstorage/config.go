@@ -0,0 +1,393 @@
+// netstorage project config.go
+package netstorage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// An Account holds the settings of a NetStorage upload account. The field
+// names used in configuration files are those of the [netstorage] section of
+// Akamai's .edgerc: host, id, key, cpcode and group, plus folder and
+// base_url.
+type Account struct {
+	Host    string // upload domain, e.g. example-nsu.akamaihd.net
+	KeyName string // id of the upload account key
+	Key     string // secret of the key
+	CPCode  string // CP code of the storage group, the root of all paths
+	Folder  string // optional directory below the CP code
+	Group   string // name of the storage group, for reference only
+	BaseURL string // optional URL passed to WithBaseURL
+}
+
+// A ConfigFormat is the syntax of a configuration file.
+type ConfigFormat int
+
+const (
+	// ConfigINI is the format of .edgerc files: a [name] section per
+	// account with key = value lines. Sections without both an id and a key,
+	// such as EdgeGrid API credentials, are skipped.
+	ConfigINI ConfigFormat = iota
+	// ConfigJSON is an object with an object of settings per account name.
+	ConfigJSON
+	// ConfigYAML is a mapping with a mapping of settings per account name.
+	// Only this subset of YAML is supported: block mappings of plain or
+	// quoted scalars, and comments.
+	ConfigYAML
+)
+
+// Config is a set of named accounts.
+type Config struct {
+	Accounts map[string]Account
+}
+
+// LoadConfig reads the accounts in filename and applies the environment
+// overrides described at ApplyEnv. The format is chosen by the file
+// extension: .json for ConfigJSON, .yaml or .yml for ConfigYAML and
+// ConfigINI for anything else, e.g. .edgerc.
+func LoadConfig(filename string) (*Config, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	format := ConfigINI
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		format = ConfigJSON
+	case ".yaml", ".yml":
+		format = ConfigYAML
+	}
+	cfg, err := ParseConfig(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("netstorage: %s: %w", filename, err)
+	}
+	cfg.ApplyEnv(os.Environ())
+	return cfg, nil
+}
+
+// ParseConfig reads accounts in the given format from r.
+func ParseConfig(r io.Reader, format ConfigFormat) (*Config, error) {
+	var sections map[string]map[string]string
+	var err error
+	switch format {
+	case ConfigINI:
+		sections, err = parseINI(r)
+	case ConfigJSON:
+		sections, err = parseJSON(r)
+	case ConfigYAML:
+		sections, err = parseYAML(r)
+	default:
+		err = fmt.Errorf("unknown config format %d", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{Accounts: make(map[string]Account)}
+	for name, settings := range sections {
+		if format == ConfigINI && (settings["id"] == "" || settings["key"] == "") {
+			continue
+		}
+		var account Account
+		for key, value := range settings {
+			account.set(key, value)
+		}
+		cfg.Accounts[name] = account
+	}
+	return cfg, nil
+}
+
+// Environment variable suffixes and the settings they override, longest
+// first so that _BASE_URL is not taken for something else.
+var envSettings = []struct{ suffix, key string }{
+	{"_BASE_URL", "base_url"},
+	{"_CPCODE", "cpcode"},
+	{"_FOLDER", "folder"},
+	{"_GROUP", "group"},
+	{"_HOST", "host"},
+	{"_KEY", "key"},
+	{"_ID", "id"},
+}
+
+// ApplyEnv overrides account settings with NETSTORAGE_<NAME>_<SETTING>
+// variables from environ, which is in the form returned by os.Environ.
+// <NAME> is the account name in upper case with characters other than
+// letters and digits replaced by underscores, and <SETTING> is one of HOST,
+// ID, KEY, CPCODE, FOLDER, GROUP and BASE_URL. Variables without a name,
+// e.g. NETSTORAGE_KEY, apply to the account "default". Variables for an
+// account that is not configured add it, named in lower case.
+func (c *Config) ApplyEnv(environ []string) {
+	if c.Accounts == nil {
+		c.Accounts = make(map[string]Account)
+	}
+	names := make(map[string]string, len(c.Accounts))
+	for name := range c.Accounts {
+		names[envName(name)] = name
+	}
+	for _, kv := range environ {
+		variable, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(variable, "NETSTORAGE") {
+			continue
+		}
+		for _, setting := range envSettings {
+			rest, ok := strings.CutSuffix(strings.TrimPrefix(variable, "NETSTORAGE"), setting.suffix)
+			if !ok {
+				continue
+			}
+			name := "default"
+			if rest != "" {
+				if rest[0] != '_' {
+					break
+				}
+				name = rest[1:]
+				if configured, ok := names[name]; ok {
+					name = configured
+				} else {
+					name = strings.ToLower(name)
+				}
+			}
+			account := c.Accounts[name]
+			account.set(setting.key, value)
+			c.Accounts[name] = account
+			break
+		}
+	}
+}
+
+// envName is the form of an account name used in environment variables.
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Names returns the sorted account names.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Accounts))
+	for name := range c.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Client returns a client for the named account. opts are applied after the
+// options derived from the account.
+func (c *Config) Client(name string, opts ...Option) (*NetstorageClient, error) {
+	account, ok := c.Accounts[name]
+	if !ok {
+		return nil, fmt.Errorf("netstorage: no account %q configured", name)
+	}
+	client, err := account.Client(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("netstorage: account %q: %w", name, err)
+	}
+	return client, nil
+}
+
+// Clients returns a client for every account, keyed by account name.
+func (c *Config) Clients(opts ...Option) (map[string]*NetstorageClient, error) {
+	clients := make(map[string]*NetstorageClient, len(c.Accounts))
+	for _, name := range c.Names() {
+		client, err := c.Client(name, opts...)
+		if err != nil {
+			return nil, err
+		}
+		clients[name] = client
+	}
+	return clients, nil
+}
+
+// Client returns a client for the account. opts are applied after the
+// options derived from the account.
+func (a Account) Client(opts ...Option) (*NetstorageClient, error) {
+	var missing []string
+	if a.Host == "" && a.BaseURL == "" {
+		missing = append(missing, "host")
+	}
+	if a.KeyName == "" {
+		missing = append(missing, "id")
+	}
+	if a.Key == "" {
+		missing = append(missing, "key")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	if a.BaseURL != "" {
+		base, err := url.Parse(a.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		opts = append([]Option{WithBaseURL(base)}, opts...)
+	}
+	folder := strings.Trim(path.Join(a.CPCode, a.Folder), "/")
+	return NewClient(a.Host, folder, a.KeyName, a.Key, opts...), nil
+}
+
+// set sets the setting key, ignoring unknown ones.
+func (a *Account) set(key, value string) {
+	switch strings.ToLower(key) {
+	case "host":
+		a.Host = value
+	case "id":
+		a.KeyName = value
+	case "key":
+		a.Key = value
+	case "cpcode":
+		a.CPCode = value
+	case "folder":
+		a.Folder = value
+	case "group":
+		a.Group = value
+	case "base_url":
+		a.BaseURL = value
+	}
+}
+
+// parseINI reads key = value lines grouped in [sections]. Lines before the
+// first section belong to "default".
+func parseINI(r io.Reader) (map[string]map[string]string, error) {
+	sections := make(map[string]map[string]string)
+	section := "default"
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[':
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed section %q", n, line)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if sections[section] == nil {
+				sections[section] = make(map[string]string)
+			}
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key = value", n)
+			}
+			if sections[section] == nil {
+				sections[section] = make(map[string]string)
+			}
+			sections[section][strings.TrimSpace(key)] = unquote(strings.TrimSpace(value))
+		}
+	}
+	return sections, scanner.Err()
+}
+
+// parseJSON reads an object of objects. Numbers, e.g. CP codes, and booleans
+// are taken as written.
+func parseJSON(r io.Reader) (map[string]map[string]string, error) {
+	var raw map[string]map[string]any
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	sections := make(map[string]map[string]string, len(raw))
+	for name, settings := range raw {
+		sections[name] = make(map[string]string, len(settings))
+		for key, value := range settings {
+			switch value.(type) {
+			case string, json.Number, bool:
+				sections[name][key] = fmt.Sprint(value)
+			default:
+				return nil, fmt.Errorf("%s.%s: expected a string or number", name, key)
+			}
+		}
+	}
+	return sections, nil
+}
+
+// parseYAML reads a two level block mapping:
+//
+//	name:
+//	  key: value
+func parseYAML(r io.Reader) (map[string]map[string]string, error) {
+	sections := make(map[string]map[string]string)
+	var section string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		line := strings.TrimLeft(text, " ")
+		if line == "" || line[0] == '#' || line == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", n)
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", n)
+		}
+		key = unquote(strings.TrimSpace(key))
+		value, err := yamlScalar(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if len(line) == len(text) {
+			if value != "" {
+				return nil, fmt.Errorf("line %d: expected a mapping for %q", n, key)
+			}
+			section = key
+			sections[section] = make(map[string]string)
+			continue
+		}
+		if section == "" {
+			return nil, fmt.Errorf("line %d: unexpected indentation", n)
+		}
+		sections[section][key] = value
+	}
+	return sections, scanner.Err()
+}
+
+// yamlScalar returns the plain or quoted scalar s without quotes and
+// trailing comment.
+func yamlScalar(s string) (string, error) {
+	switch {
+	case s == "" || s[0] == '#':
+		return "", nil
+	case s[0] == '"' || s[0] == '\'':
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 {
+			return "", errors.New("unterminated quoted string")
+		}
+		if rest := strings.TrimSpace(s[end+2:]); rest != "" && rest[0] != '#' {
+			return "", fmt.Errorf("unexpected %q after quoted string", rest)
+		}
+		return s[1 : end+1], nil
+	case strings.ContainsAny(s[:1], "[{|>&*!"):
+		return "", errors.New("only plain and quoted scalars are supported")
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s, nil
+}
+
+// unquote removes matching single or double quotes around s.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,150 @@
+// netstorage project config_test.go
+package netstorage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var expectedAccounts = map[string]Account{
+	"netstorage": {
+		Host:    "example-nsu.akamaihd.net",
+		KeyName: "upload1",
+		Key:     "c2VjcmV0==",
+		CPCode:  "123456",
+		Group:   "example",
+	},
+	"staging": {
+		Host:    "staging-nsu.akamaihd.net",
+		KeyName: "upload2",
+		Key:     "other #secret",
+		CPCode:  "654321",
+		Folder:  "site",
+	},
+}
+
+func TestParseConfig(t *testing.T) {
+	for _, test := range []struct {
+		format ConfigFormat
+		data   string
+	}{
+		{ConfigINI, `
+; EdgeGrid credentials are skipped
+[default]
+client_secret = abc
+host = akab-xxx.luna.akamaiapis.net
+
+[netstorage]
+key = c2VjcmV0==
+id = upload1
+group = example
+host = example-nsu.akamaihd.net
+cpcode = 123456
+
+[staging]
+# quoted values keep their content
+host=staging-nsu.akamaihd.net
+id=upload2
+key="other #secret"
+cpcode=654321
+folder=site
+`},
+		{ConfigJSON, `{
+	"netstorage": {"key": "c2VjcmV0==", "id": "upload1", "group": "example", "host": "example-nsu.akamaihd.net", "cpcode": 123456},
+	"staging": {"host": "staging-nsu.akamaihd.net", "id": "upload2", "key": "other #secret", "cpcode": "654321", "folder": "site"}
+}`},
+		{ConfigYAML, `---
+# accounts
+netstorage:
+  key: c2VjcmV0==
+  id: upload1
+  group: example  # comment
+  host: example-nsu.akamaihd.net
+  cpcode: 123456
+staging:
+  host: 'staging-nsu.akamaihd.net'
+  id: upload2
+  key: "other #secret" # comment
+  cpcode: 654321
+  folder: site
+`},
+	} {
+		cfg, err := ParseConfig(strings.NewReader(test.data), test.format)
+		if err != nil {
+			t.Errorf("format %d: %s", test.format, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.Accounts, expectedAccounts) {
+			t.Errorf("format %d: got %+v expected %+v", test.format, cfg.Accounts, expectedAccounts)
+		}
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		format ConfigFormat
+		data   string
+	}{
+		{ConfigINI, "[netstorage\nid = x"},
+		{ConfigINI, "[netstorage]\nid"},
+		{ConfigJSON, `{"a": {"id": ["x"]}}`},
+		{ConfigYAML, "a:\n  id: [x]"},
+		{ConfigYAML, "a: b"},
+		{ConfigYAML, "  id: x"},
+		{ConfigYAML, "a:\n  key: 'x"},
+	} {
+		if _, err := ParseConfig(strings.NewReader(test.data), test.format); err == nil {
+			t.Errorf("format %d: expected an error for %q", test.format, test.data)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := &Config{Accounts: map[string]Account{
+		"my-site": {Host: "a", KeyName: "b", Key: "c"},
+	}}
+	cfg.ApplyEnv([]string{
+		"NETSTORAGE_MY_SITE_KEY=override",
+		"NETSTORAGE_MY_SITE_BASE_URL=http://localhost:8080/ns",
+		"NETSTORAGE_HOST=default-nsu.akamaihd.net",
+		"NETSTORAGE_NEW_ID=upload3",
+		"NETSTORAGEX_HOST=ignored",
+		"PATH=/bin",
+	})
+	expected := map[string]Account{
+		"my-site": {Host: "a", KeyName: "b", Key: "override", BaseURL: "http://localhost:8080/ns"},
+		"default": {Host: "default-nsu.akamaihd.net"},
+		"new":     {KeyName: "upload3"},
+	}
+	if !reflect.DeepEqual(cfg.Accounts, expected) {
+		t.Errorf("got %+v expected %+v", cfg.Accounts, expected)
+	}
+}
+
+func TestConfigClients(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".edgerc")
+	os.WriteFile(name, []byte("[netstorage]\nhost = h\nid = i\nkey = k\ncpcode = 1\nfolder = /a/\n"), 0600)
+	t.Setenv("NETSTORAGE_NETSTORAGE_HOST", "env-host")
+	cfg, err := LoadConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients, err := cfg.Clients()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := clients["netstorage"]
+	if c == nil || c.Host != "env-host" || c.Folder != "1/a" || c.NetstorageKeyName != "i" || c.NetstorageSecret != "k" {
+		t.Errorf("unexpected client %+v", c)
+	}
+	if _, err := cfg.Client("missing"); err == nil {
+		t.Error("expected an error for an unknown account")
+	}
+	cfg.Accounts["incomplete"] = Account{Host: "h"}
+	if _, err := cfg.Client("incomplete"); err == nil || !strings.Contains(err.Error(), "missing id, key") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+// netstorage project decode_test.go
+package netstorage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestJSONDecoder(t *testing.T) {
+	setup(WithDecoder(NewJSONDecoder("stat", "dir")))
+	defer teardown()
+
+	mux.HandleFunc("/base/dir",
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Header.Get("X-Akamai-ACS-Action") {
+			case "version=1&action=stat&format=json":
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{"stat": {"directory": "/base", "file": [{"type": "dir", "name": "dir", "mtime": 1136214245, "files": 3, "bytes": 42}]}}`)
+			case "version=1&action=dir&format=json&max_entries=1":
+				// unwrapped and without a JSON content type
+				io.WriteString(w, ` {"directory": "/base/dir", "file": [{"type": "file", "name": "a", "size": 5}], "resume": {"start": "/base/dir/a"}}`)
+			case "version=1&action=du&format=xml":
+				io.WriteString(w, `<du directory="/base/dir"><du-info files="3" bytes="42"/></du>`)
+			default:
+				t.Errorf("unexpected action %s", r.Header.Get("X-Akamai-ACS-Action"))
+			}
+		},
+	)
+	ctx := context.Background()
+	stat, err := client.StatisticsContext(ctx, "dir")
+	if err != nil {
+		t.Fatalf("stat: %s", err)
+	}
+	if len(stat.Files) != 1 || stat.Files[0] != (NSFile{Type: "dir", Name: "dir", Mtime: 1136214245, Files: 3, Bytes: 42}) {
+		t.Errorf("stat: got %+v", stat)
+	}
+	page, err := client.DirWithOptions(ctx, "dir", &DirOptions{MaxEntries: 1})
+	if err != nil {
+		t.Fatalf("dir: %s", err)
+	}
+	if page.Dirctory != "/base/dir" || len(page.Files) != 1 || page.Files[0].Size != 5 || page.Resume.Start != "/base/dir/a" {
+		t.Errorf("dir: got %+v", page)
+	}
+	du, err := client.DiskUsageContext(ctx, "dir")
+	if err != nil {
+		t.Fatalf("du: %s", err)
+	}
+	if du.Info.Files != 3 || du.Info.Bytes != 42 {
+		t.Errorf("du: got %+v", du)
+	}
+}
+
+func TestJSONDecoderXMLFallback(t *testing.T) {
+	setup(WithDecoder(NewJSONDecoder()))
+	defer teardown()
+
+	mux.HandleFunc("/base/a",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkAction(t, r, "version=1&action=stat&format=json")
+			// a server that only speaks XML, labeled as plain text
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, "\n<stat directory=\"/base\"><file type=\"file\" name=\"a\" size=\"5\"/></stat>")
+		},
+	)
+	stat, err := client.Statistics("a")
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if len(stat.Files) != 1 || stat.Files[0].Size != 5 {
+		t.Errorf("got %+v", stat)
+	}
+}
+
+func TestJSONRejected(t *testing.T) {
+	setup(WithDecoder(NewJSONDecoder()))
+	defer teardown()
+
+	var actions []string
+	mux.HandleFunc("/base/a",
+		func(w http.ResponseWriter, r *http.Request) {
+			action := r.Header.Get("X-Akamai-ACS-Action")
+			actions = append(actions, action)
+			if action != "version=1&action=stat&format=xml" {
+				http.Error(w, "unsupported format", http.StatusBadRequest)
+				return
+			}
+			io.WriteString(w, `<stat directory="/base"><file type="file" name="a" size="5"/></stat>`)
+		},
+	)
+	stat, err := client.Statistics("a")
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if len(stat.Files) != 1 || stat.Files[0].Size != 5 {
+		t.Errorf("got %+v", stat)
+	}
+	if len(actions) != 2 || actions[0] != "version=1&action=stat&format=json" {
+		t.Errorf("got actions %q", actions)
+	}
+}
@@ -0,0 +1,324 @@
+// netstorage project decorators.go
+package netstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// An Operation describes a call of a NetstorageAPI method to an Interceptor.
+type Operation struct {
+	Action      string // NetStorage action, e.g. "upload" or "dir"
+	Path        string // file or directory operated on
+	Destination string // new name of a rename, target of a symlink
+	// Mutating is set for operations that change the storage.
+	Mutating bool
+	// Idempotent is set for operations that may be repeated safely. It is
+	// false for rename and quick-delete, and for uploads whose data is not
+	// an io.Seeker.
+	Idempotent bool
+}
+
+// An Interceptor wraps a call of a NetstorageAPI method. It may inspect op,
+// decide whether, how often and with which context call is made, and
+// replace its error. Unless call returns nil, the results of the method are
+// its zero values.
+type Interceptor func(ctx context.Context, op Operation, call func(ctx context.Context) error) error
+
+// Intercept returns a NetstorageAPI that passes every call to api through
+// interceptor. It is the building block of the Decorate functions.
+func Intercept(api NetstorageAPI, interceptor Interceptor) NetstorageAPI {
+	return &intercepted{api: api, intercept: interceptor}
+}
+
+// errUploadConsumed is returned when an Interceptor repeats an upload whose
+// data has already been read.
+var errUploadConsumed = errors.New("netstorage: upload data can't be sent again")
+
+type intercepted struct {
+	api       NetstorageAPI
+	intercept Interceptor
+}
+
+func (i *intercepted) UploadWithOptions(ctx context.Context, name string, r io.Reader, opts *UploadOptions) error {
+	seeker, ok := r.(io.Seeker)
+	var start int64
+	if ok {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			ok = false
+		}
+	}
+	op := Operation{Action: "upload", Path: name, Mutating: true, Idempotent: ok}
+	calls := 0
+	return i.intercept(ctx, op, func(ctx context.Context) error {
+		if calls++; calls > 1 {
+			if !ok {
+				return errUploadConsumed
+			}
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		return i.api.UploadWithOptions(ctx, name, r, opts)
+	})
+}
+
+func (i *intercepted) DownloadStream(ctx context.Context, file string) (body io.ReadCloser, info *DownloadInfo, err error) {
+	op := Operation{Action: "download", Path: file, Idempotent: true}
+	err = i.intercept(ctx, op, func(ctx context.Context) (err error) {
+		body, info, err = i.api.DownloadStream(ctx, file)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, info, nil
+}
+
+func (i *intercepted) DownloadRange(ctx context.Context, file string, offset, length int64) (body io.ReadCloser, info *DownloadInfo, err error) {
+	op := Operation{Action: "download", Path: file, Idempotent: true}
+	err = i.intercept(ctx, op, func(ctx context.Context) (err error) {
+		body, info, err = i.api.DownloadRange(ctx, file, offset, length)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, info, nil
+}
+
+func (i *intercepted) MakeDirContext(ctx context.Context, dirname string) error {
+	op := Operation{Action: "mkdir", Path: dirname, Mutating: true, Idempotent: true}
+	return i.intercept(ctx, op, func(ctx context.Context) error {
+		return i.api.MakeDirContext(ctx, dirname)
+	})
+}
+
+func (i *intercepted) RemoveDirContext(ctx context.Context, dirname string) error {
+	op := Operation{Action: "rmdir", Path: dirname, Mutating: true, Idempotent: true}
+	return i.intercept(ctx, op, func(ctx context.Context) error {
+		return i.api.RemoveDirContext(ctx, dirname)
+	})
+}
+
+func (i *intercepted) DeleteContext(ctx context.Context, file string) error {
+	op := Operation{Action: "delete", Path: file, Mutating: true, Idempotent: true}
+	return i.intercept(ctx, op, func(ctx context.Context) error {
+		return i.api.DeleteContext(ctx, file)
+	})
+}
+
+func (i *intercepted) QuickDeleteContext(ctx context.Context, dirname string) error {
+	op := Operation{Action: "quick-delete", Path: dirname, Mutating: true}
+	return i.intercept(ctx, op, func(ctx context.Context) error {
+		return i.api.QuickDeleteContext(ctx, dirname)
+	})
+}
+
+func (i *intercepted) RenameContext(ctx context.Context, file string, newname string) error {
+	op := Operation{Action: "rename", Path: file, Destination: newname, Mutating: true}
+	return i.intercept(ctx, op, func(ctx context.Context) error {
+		return i.api.RenameContext(ctx, file, newname)
+	})
+}
+
+func (i *intercepted) SymlinkContext(ctx context.Context, target, name string) error {
+	op := Operation{Action: "symlink", Path: name, Destination: target, Mutating: true, Idempotent: true}
+	return i.intercept(ctx, op, func(ctx context.Context) error {
+		return i.api.SymlinkContext(ctx, target, name)
+	})
+}
+
+func (i *intercepted) SetMtimeContext(ctx context.Context, file string, mtime time.Time) error {
+	op := Operation{Action: "mtime", Path: file, Mutating: true, Idempotent: true}
+	return i.intercept(ctx, op, func(ctx context.Context) error {
+		return i.api.SetMtimeContext(ctx, file, mtime)
+	})
+}
+
+func (i *intercepted) DirWithOptions(ctx context.Context, filepath string, opts *DirOptions) (stat Stat, err error) {
+	op := Operation{Action: "dir", Path: filepath, Idempotent: true}
+	err = i.intercept(ctx, op, func(ctx context.Context) (err error) {
+		stat, err = i.api.DirWithOptions(ctx, filepath, opts)
+		return err
+	})
+	if err != nil {
+		return Stat{}, err
+	}
+	return stat, nil
+}
+
+func (i *intercepted) ListContext(ctx context.Context, filepath string, opts *ListOptions) (result ListResult, err error) {
+	op := Operation{Action: "list", Path: filepath, Idempotent: true}
+	err = i.intercept(ctx, op, func(ctx context.Context) (err error) {
+		result, err = i.api.ListContext(ctx, filepath, opts)
+		return err
+	})
+	if err != nil {
+		return ListResult{}, err
+	}
+	return result, nil
+}
+
+func (i *intercepted) DiskUsageContext(ctx context.Context, filepath string) (du Du, err error) {
+	op := Operation{Action: "du", Path: filepath, Idempotent: true}
+	err = i.intercept(ctx, op, func(ctx context.Context) (err error) {
+		du, err = i.api.DiskUsageContext(ctx, filepath)
+		return err
+	})
+	if err != nil {
+		return Du{}, err
+	}
+	return du, nil
+}
+
+func (i *intercepted) StatisticsContext(ctx context.Context, filepath string) (stat Stat, err error) {
+	op := Operation{Action: "stat", Path: filepath, Idempotent: true}
+	err = i.intercept(ctx, op, func(ctx context.Context) (err error) {
+		stat, err = i.api.StatisticsContext(ctx, filepath)
+		return err
+	})
+	if err != nil {
+		return Stat{}, err
+	}
+	return stat, nil
+}
+
+// DecorateLogging logs every call of api to logger: successful calls at info
+// level and failed ones at warn level, with the action, path and duration.
+func DecorateLogging(api NetstorageAPI, logger *slog.Logger) NetstorageAPI {
+	return Intercept(api, func(ctx context.Context, op Operation, call func(context.Context) error) error {
+		start := time.Now()
+		err := call(ctx)
+		attrs := []slog.Attr{
+			slog.String("action", op.Action),
+			slog.String("path", op.Path),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if op.Destination != "" {
+			attrs = append(attrs, slog.String("destination", op.Destination))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err))
+			logger.LogAttrs(ctx, slog.LevelWarn, "netstorage call failed", attrs...)
+		} else {
+			logger.LogAttrs(ctx, slog.LevelInfo, "netstorage call", attrs...)
+		}
+		return err
+	})
+}
+
+// Metrics collects per-action call counters from DecorateMetrics. The zero
+// value is ready to use, and a Metrics may be shared by several decorators.
+type Metrics struct {
+	mu      sync.Mutex
+	actions map[string]ActionMetrics
+}
+
+// ActionMetrics are the counters of one action.
+type ActionMetrics struct {
+	Calls       int64         // calls made
+	Errors      int64         // calls that failed
+	Duration    time.Duration // total time spent in calls
+	MaxDuration time.Duration // longest single call
+}
+
+// Snapshot returns a copy of the counters keyed by action.
+func (m *Metrics) Snapshot() map[string]ActionMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]ActionMetrics, len(m.actions))
+	for action, metrics := range m.actions {
+		snapshot[action] = metrics
+	}
+	return snapshot
+}
+
+func (m *Metrics) record(action string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.actions == nil {
+		m.actions = make(map[string]ActionMetrics)
+	}
+	metrics := m.actions[action]
+	metrics.Calls++
+	if err != nil {
+		metrics.Errors++
+	}
+	metrics.Duration += d
+	metrics.MaxDuration = max(metrics.MaxDuration, d)
+	m.actions[action] = metrics
+}
+
+// DecorateMetrics counts the calls, errors and durations of api in m.
+func DecorateMetrics(api NetstorageAPI, m *Metrics) NetstorageAPI {
+	return Intercept(api, func(ctx context.Context, op Operation, call func(context.Context) error) error {
+		start := time.Now()
+		err := call(ctx)
+		m.record(op.Action, time.Since(start), err)
+		return err
+	})
+}
+
+// DecorateRetry retries failed calls of api as described by policy. Calls are
+// retried after network errors and NetStorage errors with status 429, 500,
+// 502, 503 and 504. Operations that are not idempotent, such as renames and
+// uploads whose data is not an io.Seeker, are never retried; WithRetry can
+// still resend their rate-limited requests where that is safe. Unlike
+// WithRetry it works with any NetstorageAPI and repeats whole operations
+// rather than single requests.
+func DecorateRetry(api NetstorageAPI, policy RetryPolicy) NetstorageAPI {
+	return Intercept(api, func(ctx context.Context, op Operation, call func(context.Context) error) error {
+		for attempt := 1; ; attempt++ {
+			err := call(ctx)
+			if err == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil || !retryable(op, err) {
+				return err
+			}
+			if err := sleep(ctx, policy.delay(attempt)); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// retryable reports whether op failing with err is worth another attempt.
+func retryable(op Operation, err error) bool {
+	if !op.Idempotent {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var nserr *NSError
+	if errors.As(err, &nserr) {
+		switch nserr.Status {
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var urlerr *url.Error
+	return errors.As(err, &urlerr)
+}
+
+// DecorateDryRun returns a NetstorageAPI that does not perform mutating
+// operations but passes them to record, which may be nil, and reports success.
+// Operations that only read are passed on to api.
+func DecorateDryRun(api NetstorageAPI, record func(Operation)) NetstorageAPI {
+	return Intercept(api, func(ctx context.Context, op Operation, call func(context.Context) error) error {
+		if !op.Mutating {
+			return call(ctx)
+		}
+		if record != nil {
+			record(op)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,156 @@
+// netstorage project decorators_test.go
+package netstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDecorateRetry(t *testing.T) {
+	var bodies []string
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(data))
+			if len(bodies) < 3 {
+				http.Error(w, "busy", http.StatusBadGateway)
+			}
+		},
+	)
+	api := DecorateRetry(client, fastRetry)
+	err := api.UploadWithOptions(context.Background(), "file.txt", strings.NewReader("hello"), nil)
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if strings.Join(bodies, ",") != "hello,hello,hello" {
+		t.Errorf("uploads not rewound between attempts: %q", bodies)
+	}
+}
+
+func TestDecorateRetryNotIdempotent(t *testing.T) {
+	var attempts int
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/a",
+		func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		},
+	)
+	api := DecorateRetry(client, fastRetry)
+	if err := api.RenameContext(context.Background(), "a", "b"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Errorf("rename sent %d times, expected once", attempts)
+	}
+}
+
+func TestDecorateRetryUnseekableUpload(t *testing.T) {
+	var bodies []string
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(data))
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		},
+	)
+	api := DecorateRetry(client, fastRetry)
+	data := io.MultiReader(strings.NewReader("hello"))
+	err := api.UploadWithOptions(context.Background(), "file.txt", data, nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+	if len(bodies) != 1 || bodies[0] != "hello" {
+		t.Errorf("upload that can't be rewound was retried: %q", bodies)
+	}
+
+	// an interceptor repeating such an upload gets an error, not an empty file
+	api = Intercept(client, func(ctx context.Context, op Operation, call func(context.Context) error) error {
+		call(ctx)
+		return call(ctx)
+	})
+	err = api.UploadWithOptions(context.Background(), "file.txt", io.MultiReader(strings.NewReader("hello")), nil)
+	if err == nil || errors.Is(err, ErrRateLimited) || len(bodies) != 2 {
+		t.Errorf("repeated upload: got %v after %d requests", err, len(bodies))
+	}
+}
+
+func TestDecorateMetricsAndLogging(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/dir1",
+		func(w http.ResponseWriter, r *http.Request) {},
+	)
+	var buf bytes.Buffer
+	var metrics Metrics
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	api := DecorateLogging(DecorateMetrics(client, &metrics), logger)
+	ctx := context.Background()
+	if err := api.MakeDirContext(ctx, "dir1"); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if err := api.MakeDirContext(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	got := metrics.Snapshot()["mkdir"]
+	if got.Calls != 2 || got.Errors != 1 || got.Duration <= 0 {
+		t.Errorf("unexpected mkdir metrics %+v", got)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "level=INFO msg=\"netstorage call\" action=mkdir path=dir1") ||
+		!strings.Contains(out, "level=WARN msg=\"netstorage call failed\" action=mkdir path=missing") {
+		t.Errorf("unexpected log output:\n%s", out)
+	}
+}
+
+func TestDecorateDryRun(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/",
+		func(w http.ResponseWriter, r *http.Request) {
+			if action := requestAction(r); action != "stat" {
+				t.Errorf("dry run sent %s", action)
+			}
+			io.WriteString(w, `<stat directory="/base"><file type="file" name="a" size="1"/></stat>`)
+		},
+	)
+	var ops []Operation
+	api := DecorateDryRun(client, func(op Operation) { ops = append(ops, op) })
+	ctx := context.Background()
+	if err := api.DeleteContext(ctx, "a"); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if err := api.RenameContext(ctx, "a", "b"); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	stat, err := api.StatisticsContext(ctx, "a")
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if len(stat.Files) != 1 {
+		t.Errorf("reads are passed on, got %+v", stat)
+	}
+	expected := []Operation{
+		{Action: "delete", Path: "a", Mutating: true, Idempotent: true},
+		{Action: "rename", Path: "a", Destination: "b", Mutating: true},
+	}
+	if len(ops) != len(expected) || ops[0] != expected[0] || ops[1] != expected[1] {
+		t.Errorf("recorded %+v, expected %+v", ops, expected)
+	}
+}
@@ -3,5 +3,12 @@
 /*
 Package netstorage implements pure go client to access Akamai NetStorage upload account of both the types - filestore and objectstore.
 You would require API key name and API key to use this package.
+
+The primitive operations of a NetstorageClient make up the NetstorageAPI
+interface, which can be mocked or wrapped by the Decorate functions. The
+higher level helpers (Walk, Sync, Batch, CopyContext, MoveContext,
+ResumeDownload, UploadFile, UploadZip and the like) are only available on
+*NetstorageClient and bypass such wrappers; client options such as WithRetry,
+WithLogger and WithDryRun apply to every request instead.
 */
 package netstorage
@@ -0,0 +1,108 @@
+// netstorage project download.go
+package netstorage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DownloadInfo holds the metadata NetStorage returns along with a download.
+type DownloadInfo struct {
+	ContentLength int64     // -1 if unknown
+	LastModified  time.Time // zero if unknown
+	ETag          string
+	Md5           string // hex md5 of the content, if the ETag carries one
+	RequestID     string // unique id the request was signed with
+}
+
+func downloadInfo(resp *http.Response) *DownloadInfo {
+	info := &DownloadInfo{
+		ContentLength: resp.ContentLength,
+		ETag:          resp.Header.Get("ETag"),
+	}
+	if resp.Request != nil {
+		info.RequestID = requestID(resp.Request)
+	}
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		if t, err := http.ParseTime(lm); err == nil {
+			info.LastModified = t
+		}
+	}
+	if md5 := strings.Trim(info.ETag, `"`); isHexMd5(md5) {
+		info.Md5 = strings.ToLower(md5)
+	}
+	return info
+}
+
+func isHexMd5(s string) bool {
+	if len(s) != 32 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// DownloadStream downloads a file and returns its still open body together with
+// the response metadata. The caller must close the returned body.
+func (client *NetstorageClient) DownloadStream(ctx context.Context, file string) (io.ReadCloser, *DownloadInfo, error) {
+	filename := path.Join(client.Folder, file)
+	req, err := client.newRequest(ctx, "GET", filename, "download", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, downloadInfo(resp), nil
+}
+
+// DownloadToFile downloads a file to localPath. The data is written to a
+// temporary file in the same directory which is renamed over localPath only
+// once the whole body has been received and its size matches Content-Length.
+func (client *NetstorageClient) DownloadToFile(ctx context.Context, file, localPath string) (*DownloadInfo, error) {
+	body, info, err := client.DownloadStream(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	// removing is a no-op once the temp file has been renamed
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, body)
+	if err == nil && info.ContentLength >= 0 && written != info.ContentLength {
+		err = fmt.Errorf("netstorage: short download of %s: got %d bytes, want %d", file, written, info.ContentLength)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tmp.Name(), localPath); err != nil {
+		return nil, err
+	}
+	if !info.LastModified.IsZero() {
+		os.Chtimes(localPath, info.LastModified, info.LastModified)
+	}
+	return info, nil
+}
@@ -0,0 +1,91 @@
+// netstorage project download_test.go
+package netstorage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestDownloadStream(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkMethod(t, r, "GET")
+			checkAction(t, r, "version=1&action=download")
+			w.Header().Set("ETag", `"5d41402abc4b2a76b9719d911017c592"`)
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			io.WriteString(w, "hello")
+		},
+	)
+	body, info, err := client.DownloadStream(context.Background(), "file.txt")
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("reading body: %s", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("got %q expected %q", data, "hello")
+	}
+	if info.ContentLength != 5 {
+		t.Errorf("ContentLength: got %d expected 5", info.ContentLength)
+	}
+	if info.Md5 != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Md5: got %q", info.Md5)
+	}
+	if info.LastModified.Year() != 2006 {
+		t.Errorf("LastModified: got %v", info.LastModified)
+	}
+}
+
+func TestDownloadToFile(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "hello")
+		},
+	)
+	local := filepath.Join(t.TempDir(), "file.txt")
+	if _, err := client.DownloadToFile(context.Background(), "file.txt", local); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	data, err := os.ReadFile(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("got %q expected %q", data, "hello")
+	}
+}
+
+func TestDownloadToFileShort(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			// announce more than is sent so the body ends early
+			w.Header().Set("Content-Length", strconv.Itoa(10))
+			io.WriteString(w, "hello")
+		},
+	)
+	dir := t.TempDir()
+	local := filepath.Join(dir, "file.txt")
+	if _, err := client.DownloadToFile(context.Background(), "file.txt", local); err == nil {
+		t.Errorf("expected an error for a truncated download")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
@@ -0,0 +1,106 @@
+// netstorage project endpoint.go
+package netstorage
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// WithBaseURL sends requests to base instead of http://<host>/. base may
+// use https, a port and a path prefix that file names are appended to, e.g.
+// to point the client at a local stand-in. The prefix is not part of the
+// signed path. The client's Host is set to the host of base.
+func WithBaseURL(base *url.URL) Option {
+	return func(client *NetstorageClient) {
+		u := *base
+		client.base = &u
+		client.Host = u.Host
+	}
+}
+
+// WithTLSConfig uses cfg for HTTPS connections, e.g. to trust a private CA
+// or to present a client certificate, and makes the client use https unless
+// WithBaseURL says otherwise. The TLS configuration is applied to a copy of
+// the http.Client given to WithHTTPClient, provided its Transport is nil or
+// an *http.Transport.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(client *NetstorageClient) {
+		client.tlsConfig = cfg
+	}
+}
+
+// LoadTLSConfig returns a TLS configuration that trusts the PEM encoded CA
+// certificates in caFile in addition to the system roots and, if certFile
+// and keyFile are given, presents that certificate to the server. Empty file
+// names are skipped.
+func LoadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("netstorage: no certificates found in %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// applyTLSConfig installs the client's TLS configuration in a copy of its
+// http.Client.
+func (client *NetstorageClient) applyTLSConfig() {
+	hc := *client.client()
+	var transport *http.Transport
+	switch t := hc.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return
+	}
+	transport.TLSClientConfig = client.tlsConfig.Clone()
+	hc.Transport = transport
+	client.httpClient = &hc
+}
+
+func (client *NetstorageClient) baseURL() *url.URL {
+	if client.base != nil {
+		return client.base
+	}
+	scheme := "http"
+	if client.tlsConfig != nil {
+		scheme = "https"
+	}
+	return &url.URL{Scheme: scheme, Host: client.Host}
+}
+
+// requestURL returns the URL of filename and the escaped path to sign for it.
+func (client *NetstorageClient) requestURL(filename string) (string, string) {
+	escaped := (&url.URL{Path: "/" + filename}).EscapedPath()
+	return strings.TrimSuffix(client.baseURL().String(), "/") + escaped, escaped[1:]
+}
+
+// signedPath recovers the path requestURL returned for signing req.
+func (client *NetstorageClient) signedPath(req *http.Request) string {
+	prefix := strings.TrimSuffix(client.baseURL().EscapedPath(), "/")
+	return strings.TrimPrefix(strings.TrimPrefix(req.URL.EscapedPath(), prefix), "/")
+}
@@ -0,0 +1,76 @@
+// netstorage project endpoint_test.go
+package netstorage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestEscaping(t *testing.T) {
+	setup()
+	defer teardown()
+
+	const escaped = "/base/dir/a%20b%3Fc%23d%25e%20%C3%BC.txt"
+	mux.HandleFunc("/",
+		func(w http.ResponseWriter, r *http.Request) {
+			if got := r.URL.EscapedPath(); got != escaped {
+				t.Errorf("path: got %q expected %q", got, escaped)
+			}
+			if r.URL.RawQuery != "" {
+				t.Errorf("unexpected query %q", r.URL.RawQuery)
+			}
+			authData := r.Header.Get("X-Akamai-ACS-Auth-Data")
+			mac := hmac.New(sha256.New, []byte("secret"))
+			fmt.Fprintf(mac, "%s%s\nx-akamai-acs-action:%s\n", authData, escaped, r.Header.Get("X-Akamai-ACS-Action"))
+			if got := r.Header.Get("X-Akamai-ACS-Auth-Sign"); got != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+				t.Errorf("signature does not cover the escaped path")
+			}
+		},
+	)
+	if err := client.Delete("dir/a b?c#d%e ü.txt"); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/ns/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			authData := r.Header.Get("X-Akamai-ACS-Auth-Data")
+			mac := hmac.New(sha256.New, []byte("secret"))
+			fmt.Fprintf(mac, "%s/base/file.txt\nx-akamai-acs-action:%s\n", authData, r.Header.Get("X-Akamai-ACS-Action"))
+			if got := r.Header.Get("X-Akamai-ACS-Auth-Sign"); got != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+				t.Errorf("signature includes the base path")
+			}
+		},
+	)
+	base, _ := url.Parse(server.URL + "/ns/")
+	client = NewClient("ignored", "base", "keyname", "secret", WithBaseURL(base))
+	if err := client.Delete("file.txt"); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	tlsMux := http.NewServeMux()
+	tlsServer := httptest.NewTLSServer(tlsMux)
+	defer tlsServer.Close()
+
+	tlsMux.HandleFunc("/base/file.txt", func(w http.ResponseWriter, r *http.Request) {})
+	pool := x509.NewCertPool()
+	pool.AddCert(tlsServer.Certificate())
+	tlsClient := NewClient(tlsServer.Listener.Addr().String(), "base", "keyname", "secret", WithTLSConfig(&tls.Config{RootCAs: pool}))
+	if err := tlsClient.Delete("file.txt"); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
@@ -0,0 +1,105 @@
+// netstorage project errors.go
+package netstorage
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Errors an *NSError matches with errors.Is, depending on its status code.
+var (
+	ErrNotFound    = errors.New("netstorage: not found")             // 404
+	ErrConflict    = errors.New("netstorage: conflict")              // 409
+	ErrForbidden   = errors.New("netstorage: forbidden")             // 403
+	ErrTooLarge    = errors.New("netstorage: entity too large")      // 413
+	ErrAuthFailed  = errors.New("netstorage: authentication failed") // 401
+	ErrRateLimited = errors.New("netstorage: rate limited")          // 429
+)
+
+var statusErrors = map[int]error{
+	http.StatusNotFound:              ErrNotFound,
+	http.StatusConflict:              ErrConflict,
+	http.StatusForbidden:             ErrForbidden,
+	http.StatusRequestEntityTooLarge: ErrTooLarge,
+	http.StatusUnauthorized:          ErrAuthFailed,
+	http.StatusTooManyRequests:       ErrRateLimited,
+}
+
+// An NSError is returned for a request NetStorage answered with an error
+// status. Use errors.Is with the Err* variables to check for common causes.
+type NSError struct {
+	Status    int
+	Message   string // raw response body
+	Action    string // action requested, e.g. "rmdir"
+	Path      string // path of the request
+	RequestID string // unique id the request was signed with
+	Reference string // Akamai reference number of the error page, if any
+	Detail    string // text of the response body without markup
+}
+
+func (e *NSError) Error() string {
+	msg := fmt.Sprintf("netstorage: %s %s: status %d", e.Action, e.Path, e.Status)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// Is reports whether target is the sentinel error for e's status.
+func (e *NSError) Is(target error) bool {
+	sentinel, ok := statusErrors[e.Status]
+	return ok && sentinel == target
+}
+
+var (
+	markup    = regexp.MustCompile(`<[^>]*>`)
+	reference = regexp.MustCompile(`Reference\s*#\s*([0-9a-fA-F.]+)`)
+)
+
+func errorFromResponse(body []byte, code int) (*NSError, error) {
+	var responseError NSError
+	responseError.Message = string(body[:])
+
+	responseError.Status = code
+	// error pages are usually HTML, keep just their text
+	text := html.UnescapeString(markup.ReplaceAllString(responseError.Message, " "))
+	responseError.Detail = strings.Join(strings.Fields(text), " ")
+	if m := reference.FindStringSubmatch(responseError.Detail); m != nil {
+		responseError.Reference = m[1]
+	}
+	return &responseError, nil
+}
+
+func getErrorDetails(response *http.Response) error {
+	body, err := getResponse(response)
+	if err != nil {
+		return err
+	}
+	nserror, _ := errorFromResponse(body, response.StatusCode)
+	if req := response.Request; req != nil {
+		nserror.Path = req.URL.Path
+		nserror.Action = requestAction(req)
+		nserror.RequestID = requestID(req)
+	}
+	return nserror
+}
+
+// requestAction returns the action name a request was signed for.
+func requestAction(req *http.Request) string {
+	params, _ := url.ParseQuery(req.Header.Get("X-Akamai-ACS-Action"))
+	return params.Get("action")
+}
+
+// requestID returns the unique id field of a request's auth data.
+func requestID(req *http.Request) string {
+	fields := strings.Split(req.Header.Get("X-Akamai-ACS-Auth-Data"), ", ")
+	if len(fields) < 5 {
+		return ""
+	}
+	return fields[4]
+}
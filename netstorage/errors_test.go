@@ -0,0 +1,47 @@
+// netstorage project errors_test.go
+package netstorage
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNSError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var requestID string
+	mux.HandleFunc("/base/dir",
+		func(w http.ResponseWriter, r *http.Request) {
+			requestID = requestIDOf(r)
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`<HTML><BODY>Directory not empty<P>Reference&#32;&#35;18&#46;6a3e1002&#46;1565093453</BODY></HTML>`))
+		},
+	)
+	err := client.RemoveDir("dir")
+	if !errors.Is(err, ErrConflict) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want an error matching only ErrConflict", err)
+	}
+	var nserr *NSError
+	if !errors.As(err, &nserr) {
+		t.Fatalf("got %T, want *NSError", err)
+	}
+	expected := NSError{
+		Status:    409,
+		Message:   nserr.Message,
+		Action:    "rmdir",
+		Path:      "/base/dir",
+		RequestID: requestID,
+		Reference: "18.6a3e1002.1565093453",
+		Detail:    "Directory not empty Reference #18.6a3e1002.1565093453",
+	}
+	if *nserr != expected {
+		t.Errorf("got %#v expected %#v", *nserr, expected)
+	}
+}
+
+func requestIDOf(r *http.Request) string {
+	return strings.Split(r.Header.Get("X-Akamai-ACS-Auth-Data"), ", ")[4]
+}
@@ -0,0 +1,274 @@
+// netstorage project fs.go
+package netstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// FS is a read-only fs.FS view of the files below a client's folder. It
+// implements fs.StatFS, fs.ReadDirFS and fs.ReadFileFS, and its files
+// implement io.Seeker so it can be served with http.FS.
+type FS struct {
+	ctx    context.Context
+	client *NetstorageClient
+}
+
+var (
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+)
+
+// NewFS returns a file system backed by client. ctx is used for all the
+// requests made through it.
+func NewFS(ctx context.Context, client *NetstorageClient) *FS {
+	return &FS{ctx: ctx, client: client}
+}
+
+// FileInfo returns f as an fs.FileInfo, which is also an fs.DirEntry.
+func (f NSFile) FileInfo() fs.FileInfo {
+	return fileInfo{f}
+}
+
+type fileInfo struct {
+	file NSFile
+}
+
+func (fi fileInfo) Name() string {
+	return path.Base(fi.file.Name)
+}
+
+func (fi fileInfo) Size() int64 {
+	return fi.file.Size
+}
+
+func (fi fileInfo) Mode() fs.FileMode {
+	switch fi.file.Type {
+	case "dir":
+		return fs.ModeDir | 0555
+	case "symlink":
+		return fs.ModeSymlink | 0444
+	}
+	return 0444
+}
+
+func (fi fileInfo) ModTime() time.Time {
+	return fi.file.ModTime()
+}
+
+func (fi fileInfo) IsDir() bool {
+	return fi.file.IsDir()
+}
+
+// Sys returns the underlying NSFile.
+func (fi fileInfo) Sys() interface{} {
+	return fi.file
+}
+
+func (fi fileInfo) Type() fs.FileMode {
+	return fi.Mode().Type()
+}
+
+func (fi fileInfo) Info() (fs.FileInfo, error) {
+	return fi, nil
+}
+
+// remote maps a valid fs path to a path relative to the client's folder.
+func remote(name string) string {
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+func fsError(op, name string, err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		err = fs.ErrNotExist
+	case errors.Is(err, ErrForbidden):
+		err = fs.ErrPermission
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	stat, err := fsys.client.StatisticsContext(fsys.ctx, remote(name))
+	if err != nil {
+		return nil, fsError("stat", name, err)
+	}
+	if len(stat.Files) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	file := stat.Files[0]
+	if name == "." {
+		file.Name = "."
+	}
+	return fileInfo{file}, nil
+}
+
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	var entries []fs.DirEntry
+	opts := &DirOptions{}
+	for {
+		stat, err := fsys.client.DirWithOptions(fsys.ctx, remote(name), opts)
+		if err != nil {
+			return nil, fsError("readdir", name, err)
+		}
+		for _, file := range stat.Files {
+			entries = append(entries, fileInfo{file})
+		}
+		if stat.Resume.Start == "" || stat.Resume.Start == opts.Start {
+			break
+		}
+		opts.Start = stat.Resume.Start
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	body, _, err := fsys.client.DownloadStream(fsys.ctx, remote(name))
+	if err != nil {
+		return nil, fsError("readfile", name, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fsError("readfile", name, err)
+	}
+	return data, nil
+}
+
+// Open stats name and returns a file or directory for it. The content of a
+// file is only downloaded once it is read.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := fsys.Stat(name)
+	if err != nil {
+		err.(*fs.PathError).Op = "open"
+		return nil, err
+	}
+	if info.IsDir() {
+		return &dirFile{fsys: fsys, name: name, info: info}, nil
+	}
+	return &file{fsys: fsys, name: name, info: info}, nil
+}
+
+// file is an open NetStorage file. Reads are served by a ranged download
+// starting at the current offset, which is restarted after a Seek.
+type file struct {
+	fsys   *FS
+	name   string
+	info   fs.FileInfo
+	body   io.ReadCloser
+	offset int64
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.offset >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		body, _, err := f.fsys.client.DownloadRange(f.fsys.ctx, remote(f.name), f.offset, -1)
+		if err != nil {
+			return 0, fsError("read", f.name, err)
+		}
+		f.body = body
+	}
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.Size()
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if offset != f.offset && f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) Close() error {
+	if f.body != nil {
+		return f.body.Close()
+	}
+	return nil
+}
+
+// dirFile is an open NetStorage directory. It is listed on the first call
+// to ReadDir.
+type dirFile struct {
+	fsys    *FS
+	name    string
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	listed  bool
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dirFile) Close() error {
+	return nil
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.listed {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.listed = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
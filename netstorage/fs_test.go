@@ -0,0 +1,109 @@
+// netstorage project fs_test.go
+package netstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// handleFiles serves stat, dir and download requests for a tree of files
+// below /base.
+func handleFiles(t *testing.T, files map[string]string) {
+	isDir := func(name string) bool {
+		for f := range files {
+			if strings.HasPrefix(f, name+"/") || name == "base" {
+				return true
+			}
+		}
+		return false
+	}
+	entry := func(name string) string {
+		if content, ok := files[name]; ok {
+			return fmt.Sprintf(`<file type="file" name="%s" size="%d" mtime="1136214245"/>`, path.Base(name), len(content))
+		}
+		return fmt.Sprintf(`<file type="dir" name="%s" mtime="1136214245"/>`, path.Base(name))
+	}
+	mux.HandleFunc("/",
+		func(w http.ResponseWriter, r *http.Request) {
+			name := strings.TrimPrefix(r.URL.Path, "/")
+			action := r.Header.Get("X-Akamai-ACS-Action")
+			_, isFile := files[name]
+			if !isFile && !isDir(name) {
+				http.NotFound(w, r)
+				return
+			}
+			switch action {
+			case "version=1&action=stat&format=xml":
+				fmt.Fprintf(w, `<stat directory="/%s">%s</stat>`, path.Dir(name), entry(name))
+			case "version=1&action=dir&format=xml":
+				children := map[string]bool{}
+				for f := range files {
+					if rest, ok := strings.CutPrefix(f, name+"/"); ok {
+						children[name+"/"+strings.Split(rest, "/")[0]] = true
+					}
+				}
+				var names []string
+				for child := range children {
+					names = append(names, child)
+				}
+				sort.Strings(names)
+				fmt.Fprintf(w, `<stat directory="/%s">`, name)
+				for _, child := range names {
+					fmt.Fprint(w, entry(child))
+				}
+				fmt.Fprint(w, `</stat>`)
+			case "version=1&action=download":
+				http.ServeContent(w, r, name, time.Time{}, strings.NewReader(files[name]))
+			default:
+				t.Errorf("unexpected action %q", action)
+			}
+		},
+	)
+}
+
+func TestFS(t *testing.T) {
+	setup()
+	defer teardown()
+	handleFiles(t, map[string]string{
+		"base/hello.txt":    "hello",
+		"base/dir/a.txt":    "aaa",
+		"base/dir/sub/b.go": "package b",
+	})
+
+	fsys := NewFS(context.Background(), client)
+	if err := fstest.TestFS(fsys, "hello.txt", "dir/a.txt", "dir/sub/b.go"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFSSeek(t *testing.T) {
+	setup()
+	defer teardown()
+	handleFiles(t, map[string]string{"base/hello.txt": "hello"})
+
+	f, err := NewFS(context.Background(), client).Open("hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	seeker := f.(io.Seeker)
+	if _, err := seeker.Seek(0, 3); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("seek with whence 3: expected fs.ErrInvalid, got %v", err)
+	}
+	if _, err := seeker.Seek(-1, io.SeekStart); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("seek before start: expected fs.ErrInvalid, got %v", err)
+	}
+	if offset, err := seeker.Seek(-2, io.SeekEnd); err != nil || offset != 3 {
+		t.Errorf("seek from end: got %d, %v", offset, err)
+	}
+}
@@ -0,0 +1,167 @@
+// netstorage project guard.go
+package netstorage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrReadOnly is returned for mutating actions of a client created
+	// WithReadOnly.
+	ErrReadOnly = errors.New("netstorage: client is read-only")
+	// ErrRefused is returned for a quick-delete the guard set with
+	// WithQuickDeleteGuard does not allow.
+	ErrRefused = errors.New("netstorage: quick-delete refused")
+)
+
+// Actions that change the storage.
+var mutatingActions = map[string]bool{
+	"upload":       true,
+	"mkdir":        true,
+	"rmdir":        true,
+	"delete":       true,
+	"quick-delete": true,
+	"rename":       true,
+	"symlink":      true,
+	"mtime":        true,
+}
+
+// quickDeleteGuard limits the directories quick-delete may be used on.
+type quickDeleteGuard struct {
+	minDepth int
+	allow    []string
+}
+
+// WithReadOnly makes the client refuse all mutating actions (uploads,
+// deletes, renames and so on) with ErrReadOnly without sending them.
+func WithReadOnly() Option {
+	return func(client *NetstorageClient) {
+		client.readOnly = true
+	}
+}
+
+// WithDryRun makes the client skip all mutating actions and report them as
+// successful. Skipped actions are logged at info level and passed to record,
+// which may be nil. Reads are sent as usual.
+func WithDryRun(record func(Operation)) Option {
+	return func(client *NetstorageClient) {
+		client.dryRun = true
+		client.record = record
+	}
+}
+
+// WithQuickDeleteGuard makes QuickDelete refuse with ErrRefused to delete
+// directories fewer than minDepth levels below the client's folder, or, if
+// any allow paths are given, directories that are not inside one of them.
+// The allow paths are relative to the client's folder as well.
+func WithQuickDeleteGuard(minDepth int, allow ...string) Option {
+	return func(client *NetstorageClient) {
+		guard := &quickDeleteGuard{minDepth: minDepth}
+		for _, dir := range allow {
+			guard.allow = append(guard.allow, path.Clean("/"+dir))
+		}
+		client.quickDelete = guard
+	}
+}
+
+// check refuses op if the guard does not allow it. inFolder tells whether
+// the directory is the client's folder or below it.
+func (g *quickDeleteGuard) check(op Operation, inFolder bool) error {
+	if g == nil || op.Action != "quick-delete" {
+		return nil
+	}
+	if !inFolder {
+		return fmt.Errorf("%w: %q is outside the client's folder", ErrRefused, op.Path)
+	}
+	dir := path.Clean("/" + op.Path)
+	if depth := strings.Count(strings.TrimSuffix(dir, "/"), "/"); depth < g.minDepth {
+		return fmt.Errorf("%w: %q is %d levels deep, at least %d required", ErrRefused, op.Path, depth, g.minDepth)
+	}
+	if len(g.allow) == 0 {
+		return nil
+	}
+	for _, allowed := range g.allow {
+		if dir == allowed || strings.HasPrefix(dir, strings.TrimSuffix(allowed, "/")+"/") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q is not in an allowed directory", ErrRefused, op.Path)
+}
+
+// guard decides whether req may be sent. It returns a non-nil response if
+// req is skipped because of dry-run mode, and an error if it is refused.
+func (client *NetstorageClient) guard(req *http.Request) (*http.Response, error) {
+	action := requestAction(req)
+	if !mutatingActions[action] {
+		return nil, nil
+	}
+	op := client.operation(req)
+	name, _ := url.PathUnescape(client.signedPath(req))
+	if err := client.quickDelete.check(op, client.inFolder(name)); err != nil {
+		return nil, err
+	}
+	if client.readOnly {
+		return nil, fmt.Errorf("%w: %s %s", ErrReadOnly, action, op.Path)
+	}
+	if !client.dryRun {
+		return nil, nil
+	}
+	client.logger().InfoContext(req.Context(), "netstorage dry run", "action", op.Action, "path", op.Path, "destination", op.Destination)
+	if client.record != nil {
+		client.record(op)
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+// operation describes req with paths relative to the client's folder.
+func (client *NetstorageClient) operation(req *http.Request) Operation {
+	name, _ := url.PathUnescape(client.signedPath(req))
+	params, _ := url.ParseQuery(req.Header.Get("X-Akamai-ACS-Action"))
+	op := Operation{
+		Action:     params.Get("action"),
+		Path:       client.relative(name),
+		Mutating:   mutatingActions[params.Get("action")],
+		Idempotent: idempotent(req) && resendable(req),
+	}
+	if destination := params.Get("destination"); destination != "" {
+		op.Destination = client.relative(destination)
+	} else if target := params.Get("target"); target != "" {
+		op.Destination = client.relative(target)
+	}
+	return op
+}
+
+// inFolder reports whether the signed path name is the client's folder or
+// below it.
+func (client *NetstorageClient) inFolder(name string) bool {
+	name = path.Clean("/" + name)
+	folder := path.Clean("/" + client.Folder)
+	return folder == "/" || name == folder || strings.HasPrefix(name, folder+"/")
+}
+
+// relative strips the client's folder from a signed path.
+func (client *NetstorageClient) relative(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	folder := strings.Trim(client.Folder, "/")
+	if folder == "" {
+		return name
+	}
+	if name == folder {
+		return ""
+	}
+	return strings.TrimPrefix(name, folder+"/")
+}
@@ -0,0 +1,115 @@
+// netstorage project guard_test.go
+package netstorage
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	setup(WithReadOnly())
+	defer teardown()
+
+	mux.HandleFunc("/base/",
+		func(w http.ResponseWriter, r *http.Request) {
+			if action := requestAction(r); action != "stat" {
+				t.Errorf("read-only client sent %s", action)
+			}
+			w.Write([]byte(`<stat directory="/base"><file type="file" name="a" size="1"/></stat>`))
+		},
+	)
+	if err := client.Delete("a"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("delete: expected ErrReadOnly, got %v", err)
+	}
+	if err := client.Upload("a", strings.NewReader("data"), ""); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("upload: expected ErrReadOnly, got %v", err)
+	}
+	if _, err := client.Statistics("a"); err != nil {
+		t.Errorf("stat: %s", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var ops []Operation
+	setup(WithDryRun(func(op Operation) { ops = append(ops, op) }))
+	defer teardown()
+
+	mux.HandleFunc("/base/",
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("dry run sent %s", requestAction(r))
+		},
+	)
+	if err := client.Upload("dir/a b", strings.NewReader("data"), ""); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if err := client.Move("a", "dir/b"); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if err := client.Symlink("a", "link"); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	// data that can't be rewound makes an upload unsafe to repeat
+	if err := client.Upload("c", io.MultiReader(strings.NewReader("data")), ""); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	expected := []Operation{
+		{Action: "upload", Path: "dir/a b", Mutating: true, Idempotent: true},
+		{Action: "mkdir", Path: "dir", Mutating: true, Idempotent: true},
+		{Action: "rename", Path: "a", Destination: "dir/b", Mutating: true},
+		{Action: "symlink", Path: "link", Destination: "a", Mutating: true, Idempotent: true},
+		{Action: "upload", Path: "c", Mutating: true},
+	}
+	if len(ops) != len(expected) {
+		t.Fatalf("recorded %+v, expected %+v", ops, expected)
+	}
+	for i := range expected {
+		if ops[i] != expected[i] {
+			t.Errorf("operation %d: got %+v expected %+v", i, ops[i], expected[i])
+		}
+	}
+}
+
+func TestQuickDeleteGuard(t *testing.T) {
+	var deleted []string
+	setup(WithQuickDeleteGuard(2, "tmp", "/builds/old/"))
+	defer teardown()
+
+	mux.HandleFunc("/base/",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkAction(t, r, "version=1&action=quick-delete&quick-delete=imreallyreallysure")
+			deleted = append(deleted, r.URL.Path)
+		},
+	)
+	for _, dir := range []string{"tmp", "/", "", "builds/new/1", "tmpfoo/1", "builds/old/../new"} {
+		if err := client.QuickDelete(dir); !errors.Is(err, ErrRefused) {
+			t.Errorf("quick-delete %q: expected ErrRefused, got %v", dir, err)
+		}
+	}
+	for _, dir := range []string{"tmp/1", "builds/old/7/"} {
+		if err := client.QuickDelete(dir); err != nil {
+			t.Errorf("quick-delete %q: %s", dir, err)
+		}
+	}
+	if strings.Join(deleted, ",") != "/base/tmp/1,/base/builds/old/7" {
+		t.Errorf("deleted %v", deleted)
+	}
+}
+
+func TestQuickDeleteGuardOutsideFolder(t *testing.T) {
+	setup(WithQuickDeleteGuard(2))
+	defer teardown()
+
+	mux.HandleFunc("/",
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("quick-delete sent for %s", r.URL.Path)
+		},
+	)
+	for _, dir := range []string{"../other/x", "a/../../other/x/y", "../basement/x"} {
+		if err := client.QuickDelete(dir); !errors.Is(err, ErrRefused) {
+			t.Errorf("quick-delete %q: expected ErrRefused, got %v", dir, err)
+		}
+	}
+}
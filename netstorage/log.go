@@ -0,0 +1,73 @@
+// netstorage project log.go
+package netstorage
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Headers whose values are never logged.
+var redactedHeaders = map[string]bool{
+	"X-Akamai-Acs-Auth-Sign": true,
+	"Authorization":          true,
+	"Proxy-Authorization":    true,
+}
+
+var discardLogger = slog.New(discardHandler{})
+
+// discardHandler is an slog.Handler that is never enabled.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// WithLogger makes the client log its requests to logger: every attempt and
+// response at debug level, including headers with signatures redacted, and
+// retries at warn level. By default nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(client *NetstorageClient) {
+		client.log = logger
+	}
+}
+
+func (client *NetstorageClient) logger() *slog.Logger {
+	if client.log != nil {
+		return client.log
+	}
+	return discardLogger
+}
+
+// requestAttrs describes a request for logging.
+func requestAttrs(req *http.Request, attempt int) []any {
+	return []any{
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.String("action", requestAction(req)),
+		slog.String("request_id", requestID(req)),
+		slog.Int("attempt", attempt),
+	}
+}
+
+// headerAttr groups the headers of a request or response, redacting
+// credentials.
+func headerAttr(header http.Header) slog.Attr {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	attrs := make([]any, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			value = "REDACTED"
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Group("headers", attrs...)
+}
@@ -0,0 +1,36 @@
+// netstorage project log_test.go
+package netstorage
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	setup(WithLogger(logger))
+	defer teardown()
+
+	var sign string
+	mux.HandleFunc("/base/dir",
+		func(w http.ResponseWriter, r *http.Request) {
+			sign = r.Header.Get("X-Akamai-ACS-Auth-Sign")
+		},
+	)
+	if err := client.MakeDir("dir"); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"netstorage request", "action=mkdir", "netstorage response", "status=200", "X-Akamai-Acs-Auth-Sign=REDACTED"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log does not contain %q:\n%s", want, out)
+		}
+	}
+	if sign == "" || strings.Contains(out, sign) {
+		t.Errorf("log contains the signature:\n%s", out)
+	}
+}
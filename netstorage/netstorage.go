@@ -2,21 +2,20 @@
 package netstorage
 
 import (
+	"context"
 	"crypto/hmac"
-	"crypto/sha256"
+	"crypto/tls"
 	"encoding/base64"
-	"encoding/xml"
 	//"errors"
-	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
-	"net/http/httputil"
+	"net/url"
 	"path"
+	"strconv"
 	"time"
-
-	"golang.org/x/net/html/charset"
 )
 
 // A NetstorageClient that implements functionality described in
@@ -27,313 +26,417 @@ type NetstorageClient struct {
 	Folder            string
 	NetstorageKeyName string
 	NetstorageSecret  string
+
+	httpClient *http.Client
+	timeout    time.Duration
+	userAgent  string
+	retry      *RetryPolicy
+	limiter    *Limiter
+	version    SignVersion
+	clock      func() time.Time
+	log        *slog.Logger
+	base       *url.URL
+	tlsConfig  *tls.Config
+	decoder    Decoder
+
+	cache       *Cache
+	readThrough bool
+
+	readOnly    bool
+	dryRun      bool
+	record      func(Operation)
+	quickDelete *quickDeleteGuard
 }
 
+// An NSFile is an entry of a dir, stat or list result.
 type NSFile struct {
-	Type  string `xml:"type,attr"`
-	Name  string `xml:"name,attr"`
-	Size  int    `xml:"size,attr"`
-	Md5   string `xml:"md5,attr"`
-	Mtime uint32 `xml:"mtime,attr"`
+	Type  string `xml:"type,attr" json:"type"` // "file", "dir" or "symlink"
+	Name  string `xml:"name,attr" json:"name"`
+	Size  int64  `xml:"size,attr" json:"size"`   // size of a file
+	Md5   string `xml:"md5,attr" json:"md5"`     // hex md5 of a file, if known
+	Mtime int64  `xml:"mtime,attr" json:"mtime"` // modification time in seconds since the epoch, see ModTime
+
+	Target   string `xml:"target,attr" json:"target"`     // target of a symlink
+	Implicit bool   `xml:"implicit,attr" json:"implicit"` // a directory created implicitly by an upload
+	Bytes    int64  `xml:"bytes,attr" json:"bytes"`       // bytes stored below a directory, if listed
+	Files    int64  `xml:"files,attr" json:"files"`       // files stored below a directory, if listed
 }
 
-type Stat struct {
-	Dirctory string   `xml:"directory,attr"`
-	Files    []NSFile `xml:"file"`
+// ModTime returns the modification time of the file.
+func (f NSFile) ModTime() time.Time {
+	return time.Unix(f.Mtime, 0)
 }
 
-type DuInfo struct {
-	Files string `xml:"files,attr"`
-	Bytes string `xml:"bytes,attr"`
+// IsDir reports whether the entry is a directory.
+func (f NSFile) IsDir() bool {
+	return f.Type == "dir"
 }
 
-type Du struct {
-	Directory string `xml:"directory,attr"`
-	Info      DuInfo `xml:"du-info"`
+// IsSymlink reports whether the entry is a symbolic link.
+func (f NSFile) IsSymlink() bool {
+	return f.Type == "symlink"
 }
 
-type NSError struct {
-	Status  int
-	Message string
+type Stat struct {
+	Dirctory string   `xml:"directory,attr" json:"directory"`
+	Files    []NSFile `xml:"file" json:"file"`
+	Resume   Resume   `xml:"resume" json:"resume"`
 }
 
-func (e *NSError) Error() string {
-	return fmt.Sprintf("status= %d and message= %s", e.Status, e.Message)
+// Resume marks where a listing cut short by max_entries continues.
+type Resume struct {
+	Start string `xml:"start,attr" json:"start"`
 }
 
-func NewClient(host, folder, keyname, key string) *NetstorageClient {
+type DuInfo struct {
+	Files int64 `xml:"files,attr" json:"files"`
+	Bytes int64 `xml:"bytes,attr" json:"bytes"`
+}
+
+type Du struct {
+	Directory string `xml:"directory,attr" json:"directory"`
+	Info      DuInfo `xml:"du-info" json:"du-info"`
+}
+
+func NewClient(host, folder, keyname, key string, opts ...Option) *NetstorageClient {
 	nsclient := &NetstorageClient{
 		Host:              host,
 		Folder:            folder,
 		NetstorageKeyName: keyname,
 		NetstorageSecret:  key,
 	}
+	for _, opt := range opts {
+		opt(nsclient)
+	}
+	if nsclient.tlsConfig != nil {
+		nsclient.applyTLSConfig()
+	}
+	if nsclient.timeout > 0 {
+		// copy so that a caller supplied (or the default) client is not modified
+		hc := *nsclient.client()
+		hc.Timeout = nsclient.timeout
+		nsclient.httpClient = &hc
+	}
 	return nsclient
 }
 
+func (client *NetstorageClient) client() *http.Client {
+	if client.httpClient != nil {
+		return client.httpClient
+	}
+	return http.DefaultClient
+}
+
 func (client *NetstorageClient) auth(httpRequest *http.Request, id string, filename string, unixTime int64, actionName string) {
 	action := fmt.Sprintf("version=1&action=%s", actionName)
-	fmt.Println("action:", action)
 	httpRequest.Header.Set("X-Akamai-ACS-Action", action)
-	authData := fmt.Sprintf("5, 0.0.0.0, 0.0.0.0, %d, %s, %s", unixTime, id, client.NetstorageKeyName)
+	authData := fmt.Sprintf("%d, 0.0.0.0, 0.0.0.0, %d, %s, %s", client.signVersion(), unixTime, id, client.NetstorageKeyName)
 	httpRequest.Header.Set("X-Akamai-ACS-Auth-Data", authData)
-	hash := hmac.New(sha256.New, []byte(client.NetstorageSecret))
+	hash := hmac.New(client.signVersion().hash(), []byte(client.NetstorageSecret))
 	fmt.Fprintf(hash, "%s/%s\nx-akamai-acs-action:%s\n", authData, filename, action)
 	httpRequest.Header.Set("X-Akamai-ACS-Auth-Sign", base64.StdEncoding.EncodeToString(hash.Sum(nil)))
 }
 
-//Upload uploads data to the path specified by the name.
-func (client *NetstorageClient) Upload(name string, r io.Reader, contentType string) error {
-	filename := path.Join(client.Folder, name)
-	req, err := http.NewRequest("PUT", fmt.Sprintf("http://%s/%s", client.Host, filename), r)
+// newRequest builds a request for filename signed for the given action.
+func (client *NetstorageClient) newRequest(ctx context.Context, method, filename, action string, body io.Reader) (*http.Request, error) {
+	rawURL, signed := client.requestURL(filename)
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	client.auth(req, filename, filename, time.Now().Unix(), "upload")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+	if client.userAgent != "" {
+		req.Header.Set("User-Agent", client.userAgent)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return getErrorDetails(resp)
+	if !client.signVersion().valid() {
+		return nil, fmt.Errorf("netstorage: unsupported signature version %d", client.signVersion())
 	}
-	fmt.Println("output: put %s", filename)
-	return nil
+	rewindable(req, body)
+	client.auth(req, nextRequestID(ctx), signed, client.now().Unix(), action)
+	return req, nil
 }
 
-//Makes a new directory specified by the dirname (equivalent of mkdir -p <path>).
-func (client *NetstorageClient) MakeDir(dirname string) error {
-	filename := path.Join(client.Folder, dirname)
-	req, err := http.NewRequest("PUT", fmt.Sprintf("http://%s/%s", client.Host, filename), nil)
+// do sends the request, throttled by the client's limiter and retrying it as
+// allowed by the client's retry policy, and turns any response other than 200
+// (or 206 for ranged downloads) into an *NSError. Mutating requests are
+// first checked against the client's read-only, dry-run and quick-delete
+// settings. On success the caller is responsible for closing the response
+// body.
+func (client *NetstorageClient) do(req *http.Request) (*http.Response, error) {
+	if resp, err := client.guard(req); resp != nil || err != nil {
+		return resp, err
+	}
+	log := client.logger()
+	id := requestID(req)
+	for attempt := 1; ; attempt++ {
+		release, err := client.limiter.acquire(req.Context(), requestAction(req))
+		if err != nil {
+			return nil, err
+		}
+		attrs := requestAttrs(req, attempt)
+		if log.Enabled(req.Context(), slog.LevelDebug) {
+			log.DebugContext(req.Context(), "netstorage request", append(attrs, headerAttr(req.Header))...)
+		}
+		if req.Body != nil {
+			// a body of unknown length is sent chunked with ContentLength 0
+			total := req.ContentLength
+			if total == 0 {
+				total = -1
+			}
+			req.Body = client.trackProgress(req, req.Body, total)
+		}
+		start := time.Now()
+		resp, err := client.client().Do(req)
+		if err != nil {
+			log.DebugContext(req.Context(), "netstorage request failed", append(attrs, "error", err, "elapsed", time.Since(start))...)
+		} else {
+			log.DebugContext(req.Context(), "netstorage response", append(attrs, "status", resp.StatusCode, "elapsed", time.Since(start))...)
+		}
+		if err == nil && (resp.StatusCode == 200 || resp.StatusCode == 206) {
+			resp.Body = &releaseBody{resp.Body, release}
+			if requestAction(req) == "download" {
+				resp.Body = client.trackProgress(req, resp.Body, resp.ContentLength)
+			}
+			return resp, nil
+		}
+		delay, retry := client.retry.backoff(attempt, req, resp, err)
+		if !retry {
+			defer release()
+			if err != nil {
+				return nil, err
+			}
+			return nil, getErrorDetails(resp)
+		}
+		if resp != nil {
+			attrs = append(attrs, "status", resp.StatusCode)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		} else {
+			attrs = append(attrs, "error", err)
+		}
+		release()
+		log.WarnContext(req.Context(), "netstorage retrying request", append(attrs, "delay", delay)...)
+		if err = sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		if req, err = client.resign(req, id, attempt+1); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// execute sends a request that has no interesting response body.
+func (client *NetstorageClient) execute(ctx context.Context, method, filename, action string, body io.Reader) error {
+	req, err := client.newRequest(ctx, method, filename, action, body)
 	if err != nil {
 		return err
 	}
-	client.auth(req, filename, filename, time.Now().Unix(), "mkdir")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.do(req)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return getErrorDetails(resp)
-	}
-	fmt.Println("output: put %s", filename)
+	resp.Body.Close()
 	return nil
 }
 
+//Upload uploads data to the path specified by the name.
+func (client *NetstorageClient) Upload(name string, r io.Reader, contentType string) error {
+	return client.UploadContext(context.Background(), name, r, contentType)
+}
+
+// UploadContext is like Upload but uses ctx for the request.
+func (client *NetstorageClient) UploadContext(ctx context.Context, name string, r io.Reader, contentType string) error {
+	return client.UploadWithOptions(ctx, name, r, &UploadOptions{ContentType: contentType})
+}
+
+//Makes a new directory specified by the dirname (equivalent of mkdir -p <path>).
+func (client *NetstorageClient) MakeDir(dirname string) error {
+	return client.MakeDirContext(context.Background(), dirname)
+}
+
+// MakeDirContext is like MakeDir but uses ctx for the request.
+func (client *NetstorageClient) MakeDirContext(ctx context.Context, dirname string) error {
+	filename := path.Join(client.Folder, dirname)
+	return client.execute(ctx, "PUT", filename, "mkdir", nil)
+}
+
 //Removes a directory. If the directory is not empty, returns 409 COnflict error
 func (client *NetstorageClient) RemoveDir(dirname string) error {
+	return client.RemoveDirContext(context.Background(), dirname)
+}
+
+// RemoveDirContext is like RemoveDir but uses ctx for the request.
+func (client *NetstorageClient) RemoveDirContext(ctx context.Context, dirname string) error {
 	filename := path.Join(client.Folder, dirname)
-	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s/%s", client.Host, filename), nil)
-	if err != nil {
-		return err
-	}
-	client.auth(req, filename, filename, time.Now().Unix(), "rmdir")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return getErrorDetails(resp)
-	}
-	fmt.Println("output: put %s", filename)
-	return nil
+	return client.execute(ctx, "POST", filename, "rmdir", nil)
 }
 
 //Deletes a file.
 func (client *NetstorageClient) Delete(file string) error {
+	return client.DeleteContext(context.Background(), file)
+}
+
+// DeleteContext is like Delete but uses ctx for the request.
+func (client *NetstorageClient) DeleteContext(ctx context.Context, file string) error {
 	filename := path.Join(client.Folder, file)
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("http://%s/%s", client.Host, filename), nil)
-	if err != nil {
-		return err
-	}
-	client.auth(req, filename, filename, time.Now().Unix(), "delete")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return getErrorDetails(resp)
-	}
-	fmt.Println("output: delete %s", filename)
-	return nil
+	return client.execute(ctx, "DELETE", filename, "delete", nil)
 }
 
 //Quick deletes a directory. If the directory is not empty, recursively delete all its content
+//Use WithQuickDeleteGuard to restrict the directories this may be used on.
 func (client *NetstorageClient) QuickDelete(dirname string) error {
+	return client.QuickDeleteContext(context.Background(), dirname)
+}
+
+// QuickDeleteContext is like QuickDelete but uses ctx for the request.
+func (client *NetstorageClient) QuickDeleteContext(ctx context.Context, dirname string) error {
 	filename := path.Join(client.Folder, dirname)
-	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s/%s", client.Host, filename), nil)
-	if err != nil {
-		return err
-	}
-	client.auth(req, filename, filename, time.Now().Unix(), "quick-delete&quick-delete=imreallyreallysure")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return getErrorDetails(resp)
-	}
-	fmt.Println("output: put %s", filename)
-	return nil
+	return client.execute(ctx, "POST", filename, "quick-delete&quick-delete=imreallyreallysure", nil)
 }
 
 //Downloads a file. If the size of file greater than 1.8gb and the type of upload account
-//is filestore, an error will be returned.
+//is filestore, an error will be returned. The caller must close the returned reader.
 func (client *NetstorageClient) Download(file string) (io.ReadCloser, error) {
-	filename := path.Join(client.Folder, file)
-	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s/%s", client.Host, filename), nil)
-	if err != nil {
-		return nil, err
-	}
-	client.auth(req, filename, filename, time.Now().Unix(), "download")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nil, getErrorDetails(resp)
+	return client.DownloadContext(context.Background(), file)
+}
+
+// DownloadContext is like Download but uses ctx for the request.
+func (client *NetstorageClient) DownloadContext(ctx context.Context, file string) (io.ReadCloser, error) {
+	if client.readThrough {
+		body, _, err := client.DownloadCached(ctx, file)
+		return body, err
 	}
-	fmt.Println("output: download %s", filename)
-	return resp.Body, nil
+	body, _, err := client.DownloadStream(ctx, file)
+	return body, err
 }
 
 //Renames a file. If another file of same name (even if the extension is different)
 //exists, 409 Conflict error will be returned.
 func (client *NetstorageClient) Rename(file string, newname string) error {
+	return client.RenameContext(context.Background(), file, newname)
+}
+
+// RenameContext is like Rename but uses ctx for the request.
+func (client *NetstorageClient) RenameContext(ctx context.Context, file string, newname string) error {
 	filename := path.Join(client.Folder, file)
 	newFilename := path.Join(client.Folder, newname)
-	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s/%s", client.Host, filename), nil)
-	if err != nil {
-		return err
+	return client.execute(ctx, "POST", filename, "rename&destination="+url.QueryEscape("/"+newFilename), nil)
+}
+
+// Move renames a file like Rename, creating the parent directory of newname
+// first if needed.
+func (client *NetstorageClient) Move(file string, newname string) error {
+	return client.MoveContext(context.Background(), file, newname)
+}
+
+// MoveContext is like Move but uses ctx for the requests.
+func (client *NetstorageClient) MoveContext(ctx context.Context, file string, newname string) error {
+	if dir := path.Dir(path.Clean("/" + newname)); dir != "/" {
+		if err := client.MakeDirContext(ctx, dir); err != nil {
+			return err
+		}
 	}
-	client.auth(req, filename, filename, time.Now().Unix(), fmt.Sprintf("delete&destination=%s", newFilename))
-	resp, err := http.DefaultClient.Do(req)
+	return client.RenameContext(ctx, file, newname)
+}
+
+// Copy copies a file to newname. NetStorage has no copy action, so the data
+// is streamed through the client: downloaded and uploaded again at the same
+// time. The upload is verified against the size and md5 of the source.
+func (client *NetstorageClient) Copy(file string, newname string) error {
+	return client.CopyContext(context.Background(), file, newname)
+}
+
+// CopyContext is like Copy but uses ctx for the requests.
+func (client *NetstorageClient) CopyContext(ctx context.Context, file string, newname string) error {
+	body, info, err := client.DownloadStream(ctx, file)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return getErrorDetails(resp)
+	defer body.Close()
+	// the download holds an in-flight slot until it is read, which the
+	// upload reading it must not wait for
+	ctx = context.WithValue(ctx, heldSlotKey{}, true)
+	opts := &UploadOptions{Md5: info.Md5}
+	if info.ContentLength > 0 {
+		opts.Size = info.ContentLength
 	}
-	fmt.Println("output: delete %s", filename)
-	return nil
+	return client.UploadWithOptions(ctx, newname, body, opts)
 }
 
 //Lists a directory.
 func (client *NetstorageClient) Dir(filepath string) (Stat, error) {
-	var stat Stat
-	filename := path.Join(client.Folder, filepath)
+	return client.DirContext(context.Background(), filepath)
+}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s/%s", client.Host, filename), nil)
-	if err != nil {
-		return stat, err
-	}
-	client.auth(req, filename, filename, time.Now().Unix(), "dir&format=xml")
+// DirContext is like Dir but uses ctx for the request.
+func (client *NetstorageClient) DirContext(ctx context.Context, filepath string) (Stat, error) {
+	return client.DirWithOptions(ctx, filepath, nil)
+}
 
-	dump1, _ := httputil.DumpRequest(req, true)
-	fmt.Println("req:")
-	fmt.Println(string(dump1))
+// DirOptions are the optional parameters of the dir action.
+type DirOptions struct {
+	Prefix     string // only list entries whose name starts with Prefix
+	Start      string // list entries after this path, e.g. a Resume.Start marker
+	End        string // stop listing at this path
+	MaxEntries int    // limit the entries of one page, zero lets the server decide
+	SlashBoth  bool   // return directory names with a trailing slash as well
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return stat, err
+func (opts *DirOptions) params() url.Values {
+	params := url.Values{}
+	if opts.Prefix != "" {
+		params.Set("prefix", opts.Prefix)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return stat, getErrorDetails(resp)
+	if opts.Start != "" {
+		params.Set("start", opts.Start)
 	}
-
-	buff, _ := getResponse(resp)
-	fmt.Println("dir data:", string(buff[:]))
-	reader := bytes.NewReader(buff)
-	decoder := xml.NewDecoder(reader)
-	decoder.CharsetReader = charset.NewReaderLabel
-	err1 := decoder.Decode(&stat)
-	if err1 != nil {
-		return stat, err1
+	if opts.End != "" {
+		params.Set("end", opts.End)
+	}
+	if opts.MaxEntries > 0 {
+		params.Set("max_entries", strconv.Itoa(opts.MaxEntries))
 	}
+	if opts.SlashBoth {
+		params.Set("slash", "both")
+	}
+	return params
+}
 
-	return stat, nil
+// DirWithOptions lists a page of a directory. When Stat.Resume.Start is set
+// the listing was cut short; pass it as DirOptions.Start to get the next page.
+func (client *NetstorageClient) DirWithOptions(ctx context.Context, filepath string, opts *DirOptions) (Stat, error) {
+	var stat Stat
+	filename := path.Join(client.Folder, filepath)
+	if opts == nil {
+		opts = &DirOptions{}
+	}
+	err := client.listing(ctx, filename, "dir", opts.params(), &stat)
+	return stat, err
 }
 
 //Enumerates the size of the files in a directory in bytes
 func (client *NetstorageClient) DiskUsage(filepath string) (Du, error) {
+	return client.DiskUsageContext(context.Background(), filepath)
+}
+
+// DiskUsageContext is like DiskUsage but uses ctx for the request.
+func (client *NetstorageClient) DiskUsageContext(ctx context.Context, filepath string) (Du, error) {
 	var nsdu Du
 	filename := path.Join(client.Folder, filepath)
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s/%s", client.Host, filename), nil)
-	if err != nil {
-		return nsdu, err
-	}
-	client.auth(req, filename, filename, time.Now().Unix(), "du&format=xml")
-
-	dump1, _ := httputil.DumpRequest(req, true)
-	fmt.Println("req:")
-	fmt.Println(string(dump1))
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nsdu, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nsdu, getErrorDetails(resp)
-	}
-
-	buff, _ := getResponse(resp)
-
-	reader := bytes.NewReader(buff)
-	decoder := xml.NewDecoder(reader)
-	decoder.CharsetReader = charset.NewReaderLabel
-	err1 := decoder.Decode(&nsdu)
-	if err1 != nil {
-		return nsdu, err1
-	}
-
-	return nsdu, nil
+	err := client.listing(ctx, filename, "du", nil, &nsdu)
+	return nsdu, err
 }
 
 //Provides stats of a file/directory
 func (client *NetstorageClient) Statistics(filepath string) (Stat, error) {
+	return client.StatisticsContext(context.Background(), filepath)
+}
+
+// StatisticsContext is like Statistics but uses ctx for the request.
+func (client *NetstorageClient) StatisticsContext(ctx context.Context, filepath string) (Stat, error) {
 	var stat Stat
 	filename := path.Join(client.Folder, filepath)
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s/%s", client.Host, filename), nil)
-	if err != nil {
-		return stat, err
-	}
-	client.auth(req, filename, filename, time.Now().Unix(), "stat&format=xml")
-
-	dump1, _ := httputil.DumpRequest(req, true)
-	fmt.Println("req:")
-	fmt.Println(string(dump1))
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return stat, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return stat, getErrorDetails(resp)
-	}
-
-	buff, _ := getResponse(resp)
-
-	reader := bytes.NewReader(buff)
-	decoder := xml.NewDecoder(reader)
-	decoder.CharsetReader = charset.NewReaderLabel
-	err1 := decoder.Decode(&stat)
-	if err1 != nil {
-		return stat, err1
-	}
-
-	return stat, nil
+	err := client.listing(ctx, filename, "stat", nil, &stat)
+	return stat, err
 }
 
 func getResponse(response *http.Response) ([]byte, error) {
@@ -344,20 +447,3 @@ func getResponse(response *http.Response) ([]byte, error) {
 	}
 	return out, err
 }
-
-func errorFromResponse(body []byte, code int) (*NSError, error) {
-	var responseError NSError
-	responseError.Message = string(body[:])
-
-	responseError.Status = code
-	return &responseError, nil
-}
-
-func getErrorDetails(response *http.Response) error {
-	body, err := getResponse(response)
-	if err != nil {
-		return err
-	}
-	nserror, _ := errorFromResponse(body, response.StatusCode)
-	return nserror
-}
@@ -0,0 +1,157 @@
+// netstorage project netstorage_test.go
+package netstorage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	mux    *http.ServeMux
+	client *NetstorageClient
+	server *httptest.Server
+)
+
+func setup(opts ...Option) {
+	// test server
+	mux = http.NewServeMux()
+	server = httptest.NewServer(mux)
+	// netstorage client configured to use test server
+	client = NewClient(strings.TrimPrefix(server.URL, "http://"), "base", "keyname", "secret", opts...)
+}
+
+func teardown() {
+	server.Close()
+}
+
+func TestUserAgent(t *testing.T) {
+	setup(WithUserAgent("ns-test/1.0"))
+	defer teardown()
+
+	mux.HandleFunc("/base/dir1",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkMethod(t, r, "PUT")
+			if got := r.Header.Get("User-Agent"); got != "ns-test/1.0" {
+				t.Errorf("User-Agent: %q, want %q", got, "ns-test/1.0")
+			}
+			checkAction(t, r, "version=1&action=mkdir")
+		},
+	)
+	if err := client.MakeDir("dir1"); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	var used bool
+	hc := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		used = true
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	setup(WithHTTPClient(hc), WithTimeout(time.Second))
+	defer teardown()
+
+	mux.HandleFunc("/base/file", func(w http.ResponseWriter, r *http.Request) {})
+	if err := client.Delete("file"); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+	if !used {
+		t.Errorf("custom http.Client was not used")
+	}
+	if hc.Timeout != 0 {
+		t.Errorf("WithTimeout modified the caller's http.Client")
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/file", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request sent with a canceled context")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.DeleteContext(ctx, "file"); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestDirAttributes(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/dir", func(w http.ResponseWriter, r *http.Request) {
+		checkAction(t, r, "version=1&action=dir&format=xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?>
+<stat directory="/base/dir">
+<file type="file" name="big.iso" mtime="1136214245" size="5000000000" md5="5d41402abc4b2a76b9719d911017c592"/>
+<file type="dir" name="sub" mtime="1136214245" implicit="true" bytes="5000000000" files="12"/>
+<file type="symlink" name="latest" mtime="1136214245" target="/base/dir/big.iso"/>
+</stat>`))
+	})
+	stat, err := client.Dir("dir")
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	expected := []NSFile{
+		{Type: "file", Name: "big.iso", Mtime: 1136214245, Size: 5000000000, Md5: "5d41402abc4b2a76b9719d911017c592"},
+		{Type: "dir", Name: "sub", Mtime: 1136214245, Implicit: true, Bytes: 5000000000, Files: 12},
+		{Type: "symlink", Name: "latest", Mtime: 1136214245, Target: "/base/dir/big.iso"},
+	}
+	if len(stat.Files) != len(expected) {
+		t.Fatalf("got %+v", stat.Files)
+	}
+	for i, file := range stat.Files {
+		if file != expected[i] {
+			t.Errorf("got %+v expected %+v", file, expected[i])
+		}
+	}
+	file := stat.Files[0]
+	if !file.ModTime().Equal(time.Unix(1136214245, 0)) || file.IsDir() || file.IsSymlink() {
+		t.Errorf("unexpected helpers for %+v", file)
+	}
+	if !stat.Files[1].IsDir() || !stat.Files[2].IsSymlink() {
+		t.Errorf("IsDir/IsSymlink wrong for %+v", stat.Files[1:])
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/dir", func(w http.ResponseWriter, r *http.Request) {
+		checkAction(t, r, "version=1&action=du&format=xml")
+		w.Write([]byte(`<du directory="/base/dir"><du-info files="12" bytes="5000000000"/></du>`))
+	})
+	du, err := client.DiskUsage("dir")
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if du.Info.Files != 12 || du.Info.Bytes != 5000000000 {
+		t.Errorf("got %+v", du.Info)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func checkMethod(t *testing.T, r *http.Request, want string) {
+	if got := r.Method; got != want {
+		t.Errorf("Request method: %v, want %v", got, want)
+	}
+}
+
+func checkAction(t *testing.T, r *http.Request, want string) {
+	if got := r.Header.Get("X-Akamai-ACS-Action"); got != want {
+		t.Errorf("X-Akamai-ACS-Action: %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,660 @@
+// netstoragetest project server.go
+
+/*
+Package netstoragetest provides an in-memory fake of the NetStorage HTTP API
+for testing code built on netstorage.NetstorageClient without network access.
+*/
+package netstoragetest
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ap/netstorage"
+)
+
+// A Server is a fake NetStorage upload account. It checks the signature of
+// every request, rejects reused request ids, and keeps the files it serves in
+// memory. Paths are absolute, e.g. "/12345/dir/file.txt". Like NetStorage,
+// it answers requests for paths that are not clean, such as "//12345/file",
+// with 400 Bad Request, so clients should use a folder without a leading
+// slash.
+type Server struct {
+	*httptest.Server
+	KeyName string
+	Key     string
+
+	mu       sync.Mutex
+	entries  map[string]*entry
+	faults   []*Fault
+	ids      map[string]bool
+	requests []Request
+}
+
+type entry struct {
+	typ    string // "file", "dir" or "symlink"
+	data   []byte
+	mtime  time.Time
+	target string
+	// implicit is set for directories created as parents of other entries
+	implicit bool
+	zip      *zip.Reader // index of an archive uploaded with index-zip=1
+}
+
+// A Request is a request the Server received.
+type Request struct {
+	Method    string
+	Path      string
+	Action    string // e.g. "upload"
+	Params    url.Values
+	RequestID string
+}
+
+// A Fault makes the Server misbehave for matching requests.
+type Fault struct {
+	Action  string        // action to match, empty for any
+	Path    string        // path.Match pattern of paths to match, empty for any
+	Latency time.Duration // delay before the request is handled
+	Status  int           // if set, answer with this status instead
+	Times   int           // number of requests affected, zero for all
+}
+
+// NewServer starts a fake NetStorage accepting requests signed with the given
+// upload account key. The caller should call Close when finished.
+func NewServer(keyName, key string) *Server {
+	s := &Server{
+		KeyName: keyName,
+		Key:     key,
+		entries: map[string]*entry{"/": {typ: "dir", mtime: time.Now()}},
+		ids:     make(map[string]bool),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a client for the Server working below folder.
+func (s *Server) Client(folder string, opts ...netstorage.Option) *netstorage.NetstorageClient {
+	return netstorage.NewClient(s.Listener.Addr().String(), folder, s.KeyName, s.Key, opts...)
+}
+
+// PutFile stores a file, creating its parent directories.
+func (s *Server) PutFile(name string, data []byte, mtime time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name = clean(name)
+	s.mkdirAll(path.Dir(name), mtime)
+	s.entries[name] = &entry{typ: "file", data: append([]byte(nil), data...), mtime: mtime}
+}
+
+// File returns the content of a stored file.
+func (s *Server) File(name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[clean(name)]
+	if !ok || e.typ != "file" {
+		return nil, false
+	}
+	return append([]byte(nil), e.data...), true
+}
+
+// Exists reports whether a file, directory or symlink is stored at name.
+func (s *Server) Exists(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.entries[clean(name)]
+	return ok
+}
+
+// InjectFault adds a fault. Faults are checked in the order they were added
+// and the first matching one applies.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests received so far, including rejected ones.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func clean(name string) string {
+	return path.Clean("/" + name)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	params, _ := url.ParseQuery(r.Header.Get("X-Akamai-ACS-Action"))
+	name := r.URL.Path
+	req := Request{Method: r.Method, Path: name, Action: params.Get("action"), Params: params}
+
+	id, err := s.verify(r)
+	req.RequestID = id
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	fault := s.fault(req)
+	s.mu.Unlock()
+	if err != nil {
+		httpError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if name != clean(name) {
+		// NetStorage does not normalize paths such as "//123/a/../b"
+		httpError(w, http.StatusBadRequest, "malformed path "+name)
+		return
+	}
+	if fault != nil {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 {
+			httpError(w, fault.Status, "injected fault")
+			return
+		}
+	}
+
+	var body []byte
+	if r.Method == "PUT" || r.Method == "POST" {
+		if body, err = io.ReadAll(r.Body); err != nil {
+			httpError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch req.Action {
+	case "upload":
+		s.upload(w, name, params, body)
+	case "download":
+		s.download(w, r, name)
+	case "dir":
+		s.dir(w, name, params)
+	case "list":
+		s.list(w, name, params)
+	case "stat":
+		s.stat(w, name, params)
+	case "du":
+		s.du(w, name, params)
+	case "mkdir":
+		s.mkdir(w, name)
+	case "rmdir":
+		s.rmdir(w, name)
+	case "delete":
+		s.delete(w, name)
+	case "quick-delete":
+		s.quickDelete(w, name, params)
+	case "rename":
+		s.rename(w, name, params)
+	case "symlink":
+		s.symlink(w, name, params)
+	case "mtime":
+		s.setMtime(w, name, params)
+	default:
+		httpError(w, http.StatusBadRequest, "unsupported action "+req.Action)
+	}
+}
+
+// verify checks the signature of r and returns its request id.
+func (s *Server) verify(r *http.Request) (string, error) {
+	authData := r.Header.Get("X-Akamai-ACS-Auth-Data")
+	fields := strings.Split(authData, ", ")
+	if len(fields) != 6 {
+		return "", fmt.Errorf("malformed auth data %q", authData)
+	}
+	id := fields[4]
+	if fields[5] != s.KeyName {
+		return id, fmt.Errorf("unknown key %q", fields[5])
+	}
+	var newHash func() hash.Hash
+	switch fields[0] {
+	case "3":
+		newHash = md5.New
+	case "4":
+		newHash = sha1.New
+	case "5":
+		newHash = sha256.New
+	default:
+		return id, fmt.Errorf("unsupported signature version %q", fields[0])
+	}
+	mac := hmac.New(newHash, []byte(s.Key))
+	fmt.Fprintf(mac, "%s%s\nx-akamai-acs-action:%s\n", authData, r.URL.EscapedPath(), r.Header.Get("X-Akamai-ACS-Action"))
+	if r.Header.Get("X-Akamai-ACS-Auth-Sign") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+		return id, fmt.Errorf("signature mismatch")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ids[id] {
+		return id, fmt.Errorf("request id %q reused", id)
+	}
+	s.ids[id] = true
+	return id, nil
+}
+
+// fault returns the fault applying to req, if any.
+func (s *Server) fault(req Request) *Fault {
+	for i, f := range s.faults {
+		if f.Action != "" && f.Action != req.Action {
+			continue
+		}
+		if f.Path != "" {
+			if ok, _ := path.Match(f.Path, req.Path); !ok {
+				continue
+			}
+		}
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func httpError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<HTML><HEAD><TITLE>%s</TITLE></HEAD><BODY>%s</BODY></HTML>", http.StatusText(status), message)
+}
+
+func (s *Server) mkdirAll(name string, mtime time.Time) bool {
+	for dir := name; ; dir = path.Dir(dir) {
+		if e, ok := s.entries[dir]; ok {
+			if e.typ != "dir" {
+				return false
+			}
+		} else {
+			s.entries[dir] = &entry{typ: "dir", mtime: mtime, implicit: true}
+		}
+		if dir == "/" {
+			return true
+		}
+	}
+}
+
+// children returns the sorted paths of the entries directly below dir.
+func (s *Server) children(dir string) []string {
+	var names []string
+	for name := range s.entries {
+		if name != "/" && path.Dir(name) == dir {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) upload(w http.ResponseWriter, name string, params url.Values, body []byte) {
+	if e, ok := s.entries[name]; ok && e.typ == "dir" {
+		httpError(w, http.StatusConflict, "is a directory")
+		return
+	}
+	for param, sum := range map[string]hash.Hash{"md5": md5.New(), "sha1": sha1.New(), "sha256": sha256.New()} {
+		if want := params.Get(param); want != "" {
+			sum.Write(body)
+			if !strings.EqualFold(hex.EncodeToString(sum.Sum(nil)), want) {
+				httpError(w, http.StatusConflict, param+" mismatch")
+				return
+			}
+		}
+	}
+	if size := params.Get("size"); size != "" && size != strconv.Itoa(len(body)) {
+		httpError(w, http.StatusConflict, "size mismatch")
+		return
+	}
+	mtime := time.Now()
+	if m := params.Get("mtime"); m != "" {
+		secs, _ := strconv.ParseInt(m, 10, 64)
+		mtime = time.Unix(secs, 0)
+	}
+	if !s.mkdirAll(path.Dir(name), mtime) {
+		httpError(w, http.StatusConflict, "parent is not a directory")
+		return
+	}
+	e := &entry{typ: "file", data: body, mtime: mtime}
+	if params.Get("index-zip") == "1" {
+		index, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			httpError(w, http.StatusBadRequest, "not a zip archive")
+			return
+		}
+		e.zip = index
+	}
+	s.entries[name] = e
+}
+
+func (s *Server) download(w http.ResponseWriter, r *http.Request, name string) {
+	e, ok := s.entries[name]
+	if !ok {
+		e, ok = s.zipMember(name)
+	}
+	if !ok || e.typ != "file" {
+		httpError(w, http.StatusNotFound, "not found")
+		return
+	}
+	sum := md5.Sum(e.data)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	http.ServeContent(w, r, name, e.mtime, bytes.NewReader(e.data))
+}
+
+// zipMember looks name up in the indexed archive among its parents.
+func (s *Server) zipMember(name string) (*entry, bool) {
+	for archive := path.Dir(name); archive != "/"; archive = path.Dir(archive) {
+		e, ok := s.entries[archive]
+		if !ok {
+			continue
+		}
+		if e.zip == nil {
+			return nil, false
+		}
+		f, err := e.zip.Open(strings.TrimPrefix(name, archive+"/"))
+		if err != nil {
+			return nil, false
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			return nil, false
+		}
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, false
+		}
+		return &entry{typ: "file", data: data, mtime: info.ModTime()}, true
+	}
+	return nil, false
+}
+
+type xmlFile struct {
+	Type     string `xml:"type,attr" json:"type"`
+	Name     string `xml:"name,attr" json:"name"`
+	Size     int64  `xml:"size,attr,omitempty" json:"size,omitempty"`
+	Md5      string `xml:"md5,attr,omitempty" json:"md5,omitempty"`
+	Mtime    int64  `xml:"mtime,attr" json:"mtime"`
+	Target   string `xml:"target,attr,omitempty" json:"target,omitempty"`
+	Implicit bool   `xml:"implicit,attr,omitempty" json:"implicit,omitempty"`
+	Bytes    int64  `xml:"bytes,attr,omitempty" json:"bytes,omitempty"`
+	Files    int64  `xml:"files,attr,omitempty" json:"files,omitempty"`
+}
+
+type xmlResume struct {
+	Start string `xml:"start,attr" json:"start"`
+}
+
+func (s *Server) xmlFile(name, displayName string) xmlFile {
+	e := s.entries[name]
+	f := xmlFile{Type: e.typ, Name: displayName, Mtime: e.mtime.Unix(), Target: e.target, Implicit: e.implicit}
+	switch e.typ {
+	case "file":
+		sum := md5.Sum(e.data)
+		f.Size = int64(len(e.data))
+		f.Md5 = hex.EncodeToString(sum[:])
+	case "dir":
+		f.Files, f.Bytes = s.usage(name)
+	}
+	return f
+}
+
+// usage counts the files below dir and their bytes.
+func (s *Server) usage(dir string) (files, bytes int64) {
+	for name, e := range s.entries {
+		if e.typ == "file" && strings.HasPrefix(name, strings.TrimSuffix(dir, "/")+"/") {
+			files++
+			bytes += int64(len(e.data))
+		}
+	}
+	return files, bytes
+}
+
+// writeListing writes the listing v with the given root element as XML, or
+// as JSON if requested with format=json. The JSON is the layout
+// netstorage.NewJSONDecoder expects, not one published for NetStorage.
+func writeListing(w http.ResponseWriter, params url.Values, root string, v interface{}) {
+	if params.Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{root: v})
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(v)
+}
+
+// page limits names to those after start and up to end, and to max_entries
+// entries, returning the resume marker if names were left out.
+func page(names []string, params url.Values) ([]string, *xmlResume) {
+	var selected []string
+	start, end := params.Get("start"), params.Get("end")
+	for _, name := range names {
+		if (start == "" || name > start) && (end == "" || name <= end) {
+			selected = append(selected, name)
+		}
+	}
+	if n, _ := strconv.Atoi(params.Get("max_entries")); n > 0 && len(selected) > n {
+		selected = selected[:n]
+		return selected, &xmlResume{Start: selected[n-1]}
+	}
+	return selected, nil
+}
+
+func (s *Server) dir(w http.ResponseWriter, name string, params url.Values) {
+	e, ok := s.entries[name]
+	if !ok || e.typ != "dir" {
+		httpError(w, http.StatusNotFound, "not found")
+		return
+	}
+	var names []string
+	for _, child := range s.children(name) {
+		if strings.HasPrefix(path.Base(child), params.Get("prefix")) {
+			names = append(names, child)
+		}
+	}
+	names, resume := page(names, params)
+	result := struct {
+		XMLName   xml.Name   `xml:"stat" json:"-"`
+		Directory string     `xml:"directory,attr" json:"directory"`
+		Files     []xmlFile  `xml:"file" json:"file"`
+		Resume    *xmlResume `xml:"resume" json:"resume"`
+	}{Directory: name, Resume: resume}
+	for _, child := range names {
+		f := s.xmlFile(child, path.Base(child))
+		if f.Type == "dir" && params.Get("slash") == "both" {
+			f.Name += "/"
+		}
+		result.Files = append(result.Files, f)
+	}
+	writeListing(w, params, "stat", result)
+}
+
+// list lists the files below name recursively. If name is not a directory
+// it is taken as a resume marker and the files of the whole account after it
+// are listed, so resumed listings should be bounded with end.
+func (s *Server) list(w http.ResponseWriter, name string, params url.Values) {
+	var names []string
+	if e, ok := s.entries[name]; ok && e.typ == "dir" {
+		for entry, e := range s.entries {
+			if e.typ != "dir" && strings.HasPrefix(entry, strings.TrimSuffix(name, "/")+"/") {
+				names = append(names, entry)
+			}
+		}
+	} else {
+		for entry, e := range s.entries {
+			if e.typ != "dir" && entry > name {
+				names = append(names, entry)
+			}
+		}
+	}
+	sort.Strings(names)
+	names, resume := page(names, params)
+	result := struct {
+		XMLName xml.Name   `xml:"list" json:"-"`
+		Files   []xmlFile  `xml:"file" json:"file"`
+		Resume  *xmlResume `xml:"resume" json:"resume"`
+	}{Resume: resume}
+	for _, entry := range names {
+		result.Files = append(result.Files, s.xmlFile(entry, strings.TrimPrefix(entry, "/")))
+	}
+	writeListing(w, params, "list", result)
+}
+
+func (s *Server) stat(w http.ResponseWriter, name string, params url.Values) {
+	if _, ok := s.entries[name]; !ok {
+		httpError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeListing(w, params, "stat", struct {
+		XMLName   xml.Name  `xml:"stat" json:"-"`
+		Directory string    `xml:"directory,attr" json:"directory"`
+		Files     []xmlFile `xml:"file" json:"file"`
+	}{Directory: path.Dir(name), Files: []xmlFile{s.xmlFile(name, path.Base(name))}})
+}
+
+func (s *Server) du(w http.ResponseWriter, name string, params url.Values) {
+	e, ok := s.entries[name]
+	if !ok || e.typ != "dir" {
+		httpError(w, http.StatusNotFound, "not found")
+		return
+	}
+	files, bytes := s.usage(name)
+	writeListing(w, params, "du", xmlDu{Directory: name, Info: xmlDuInfo{Files: files, Bytes: bytes}})
+}
+
+type xmlDuInfo struct {
+	Files int64 `xml:"files,attr" json:"files"`
+	Bytes int64 `xml:"bytes,attr" json:"bytes"`
+}
+
+type xmlDu struct {
+	XMLName   xml.Name  `xml:"du" json:"-"`
+	Directory string    `xml:"directory,attr" json:"directory"`
+	Info      xmlDuInfo `xml:"du-info" json:"du-info"`
+}
+
+func (s *Server) mkdir(w http.ResponseWriter, name string) {
+	if !s.mkdirAll(name, time.Now()) {
+		httpError(w, http.StatusConflict, "not a directory")
+		return
+	}
+	s.entries[name].implicit = false
+}
+
+func (s *Server) rmdir(w http.ResponseWriter, name string) {
+	e, ok := s.entries[name]
+	switch {
+	case !ok:
+		httpError(w, http.StatusNotFound, "not found")
+	case e.typ != "dir":
+		httpError(w, http.StatusConflict, "not a directory")
+	case len(s.children(name)) > 0:
+		httpError(w, http.StatusConflict, "directory not empty")
+	default:
+		delete(s.entries, name)
+	}
+}
+
+func (s *Server) delete(w http.ResponseWriter, name string) {
+	e, ok := s.entries[name]
+	switch {
+	case !ok:
+		httpError(w, http.StatusNotFound, "not found")
+	case e.typ == "dir":
+		httpError(w, http.StatusConflict, "is a directory")
+	default:
+		delete(s.entries, name)
+	}
+}
+
+func (s *Server) quickDelete(w http.ResponseWriter, name string, params url.Values) {
+	e, ok := s.entries[name]
+	switch {
+	case params.Get("quick-delete") != "imreallyreallysure":
+		httpError(w, http.StatusForbidden, "quick-delete not confirmed")
+	case !ok:
+		httpError(w, http.StatusNotFound, "not found")
+	case e.typ != "dir" || name == "/":
+		httpError(w, http.StatusConflict, "not a removable directory")
+	default:
+		for entry := range s.entries {
+			if entry == name || strings.HasPrefix(entry, name+"/") {
+				delete(s.entries, entry)
+			}
+		}
+	}
+}
+
+func (s *Server) rename(w http.ResponseWriter, name string, params url.Values) {
+	destination := clean(params.Get("destination"))
+	e, ok := s.entries[name]
+	switch {
+	case !ok:
+		httpError(w, http.StatusNotFound, "not found")
+	case e.typ == "dir":
+		httpError(w, http.StatusConflict, "can't rename a directory")
+	case s.entries[destination] != nil:
+		httpError(w, http.StatusConflict, "destination exists")
+	case s.entries[path.Dir(destination)] == nil || s.entries[path.Dir(destination)].typ != "dir":
+		httpError(w, http.StatusConflict, "destination directory does not exist")
+	default:
+		delete(s.entries, name)
+		s.entries[destination] = e
+	}
+}
+
+func (s *Server) symlink(w http.ResponseWriter, name string, params url.Values) {
+	if e, ok := s.entries[name]; ok && e.typ == "dir" {
+		httpError(w, http.StatusConflict, "is a directory")
+		return
+	}
+	if !s.mkdirAll(path.Dir(name), time.Now()) {
+		httpError(w, http.StatusConflict, "parent is not a directory")
+		return
+	}
+	s.entries[name] = &entry{typ: "symlink", target: params.Get("target"), mtime: time.Now()}
+}
+
+func (s *Server) setMtime(w http.ResponseWriter, name string, params url.Values) {
+	e, ok := s.entries[name]
+	if !ok || e.typ == "dir" {
+		httpError(w, http.StatusNotFound, "not found")
+		return
+	}
+	secs, err := strconv.ParseInt(params.Get("mtime"), 10, 64)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "invalid mtime")
+		return
+	}
+	e.mtime = time.Unix(secs, 0)
+}
@@ -0,0 +1,255 @@
+// netstoragetest project server_test.go
+package netstoragetest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ap/netstorage"
+)
+
+func TestFileOperations(t *testing.T) {
+	server := NewServer("keyname", "secret")
+	defer server.Close()
+	client := server.Client("123")
+	ctx := context.Background()
+
+	if err := client.Upload("dir/hello.txt", strings.NewReader("hello"), "text/plain"); err != nil {
+		t.Fatalf("upload: %s", err)
+	}
+	if data, ok := server.File("/123/dir/hello.txt"); !ok || string(data) != "hello" {
+		t.Errorf("stored file: got %q, %v", data, ok)
+	}
+
+	body, info, err := client.DownloadStream(ctx, "dir/hello.txt")
+	if err != nil {
+		t.Fatalf("download: %s", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "hello" || info.Md5 != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("download: got %q with md5 %q", data, info.Md5)
+	}
+
+	stat, err := client.Statistics("dir/hello.txt")
+	if err != nil || len(stat.Files) != 1 || stat.Files[0].Size != 5 {
+		t.Errorf("stat: got %+v, %v", stat, err)
+	}
+	if err := client.SetMtime("dir/hello.txt", time.Unix(1136214245, 0)); err != nil {
+		t.Errorf("mtime: %s", err)
+	}
+	if stat, _ := client.Statistics("dir/hello.txt"); stat.Files[0].Mtime != 1136214245 {
+		t.Errorf("mtime not set: %+v", stat)
+	}
+
+	if err := client.Symlink("dir/hello.txt", "link"); err != nil {
+		t.Errorf("symlink: %s", err)
+	}
+	if err := client.Rename("dir/hello.txt", "dir/renamed.txt"); err != nil {
+		t.Errorf("rename: %s", err)
+	}
+	if server.Exists("/123/dir/hello.txt") || !server.Exists("/123/dir/renamed.txt") {
+		t.Errorf("rename did not move the file")
+	}
+
+	du, err := client.DiskUsage("")
+	if err != nil || du.Info.Files != 1 || du.Info.Bytes != 5 {
+		t.Errorf("du: got %+v, %v", du, err)
+	}
+
+	if err := client.RemoveDir("dir"); !errors.Is(err, netstorage.ErrConflict) {
+		t.Errorf("rmdir of a non-empty directory: got %v", err)
+	}
+	if err := client.Delete("dir/renamed.txt"); err != nil {
+		t.Errorf("delete: %s", err)
+	}
+	if err := client.RemoveDir("dir"); err != nil {
+		t.Errorf("rmdir: %s", err)
+	}
+	if _, err := client.Statistics("dir"); !errors.Is(err, netstorage.ErrNotFound) {
+		t.Errorf("stat of a removed directory: got %v", err)
+	}
+}
+
+func TestListings(t *testing.T) {
+	server := NewServer("keyname", "secret")
+	defer server.Close()
+	client := server.Client("123")
+	for _, name := range []string{"/123/a/1", "/123/a/2", "/123/a/b/3", "/123/c"} {
+		server.PutFile(name, []byte(name), time.Now())
+	}
+
+	var walked []string
+	err := client.WalkWithOptions(context.Background(), "", func(name string, file netstorage.NSFile, err error) error {
+		walked = append(walked, name)
+		return err
+	}, &netstorage.WalkOptions{PageSize: 1})
+	if err != nil {
+		t.Fatalf("walk: %s", err)
+	}
+	if got := strings.Join(walked, " "); got != ". a a/1 a/2 a/b a/b/3 c" {
+		t.Errorf("walk: got %s", got)
+	}
+
+	var listed []string
+	opts := &netstorage.ListOptions{MaxEntries: 2, End: "/123/a0"}
+	for {
+		page, err := client.List("a", opts)
+		if err != nil {
+			t.Fatalf("list: %s", err)
+		}
+		for _, f := range page.Files {
+			listed = append(listed, f.Name)
+		}
+		if !page.More() {
+			break
+		}
+		opts = page.Next(opts)
+	}
+	if got := strings.Join(listed, " "); got != "123/a/1 123/a/2 123/a/b/3" {
+		t.Errorf("list: got %s", got)
+	}
+
+	if err := client.QuickDelete("a"); err != nil {
+		t.Errorf("quick-delete: %s", err)
+	}
+	if server.Exists("/123/a/b/3") || !server.Exists("/123/c") {
+		t.Errorf("quick-delete removed the wrong files")
+	}
+}
+
+func TestSignatureChecked(t *testing.T) {
+	server := NewServer("keyname", "secret")
+	defer server.Close()
+
+	wrongKey := netstorage.NewClient(server.Listener.Addr().String(), "123", "keyname", "wrong")
+	if err := wrongKey.MakeDir("dir"); !errors.Is(err, netstorage.ErrForbidden) {
+		t.Errorf("got %v, want ErrForbidden", err)
+	}
+	client := server.Client("123")
+	client.MakeDirContext(netstorage.ContextWithRequestID(context.Background(), "same"), "dir")
+	if err := client.MakeDirContext(netstorage.ContextWithRequestID(context.Background(), "same"), "dir"); !errors.Is(err, netstorage.ErrForbidden) {
+		t.Errorf("reused request id: got %v, want ErrForbidden", err)
+	}
+}
+
+func TestRequestIDAcrossRequests(t *testing.T) {
+	server := NewServer("keyname", "secret")
+	defer server.Close()
+	server.PutFile("/123/a.txt", []byte("data"), time.Now())
+
+	// MoveContext makes a mkdir and a rename request with the one context
+	client := server.Client("123")
+	ctx := netstorage.ContextWithRequestID(context.Background(), "move-1")
+	if err := client.MoveContext(ctx, "a.txt", "dir/b.txt"); err != nil {
+		t.Fatalf("move: %s", err)
+	}
+	if !server.Exists("/123/dir/b.txt") {
+		t.Errorf("file not moved")
+	}
+	var ids []string
+	for _, req := range server.Requests() {
+		ids = append(ids, req.RequestID)
+	}
+	if strings.Join(ids, " ") != "move-1 move-1.2" {
+		t.Errorf("got request ids %q", ids)
+	}
+}
+
+func TestMalformedPath(t *testing.T) {
+	server := NewServer("keyname", "secret")
+	defer server.Close()
+
+	// a leading slash in the folder doubles the one of the URL path
+	client := server.Client("/123")
+	var nserr *netstorage.NSError
+	if err := client.MakeDir("dir"); !errors.As(err, &nserr) || nserr.Status != 400 {
+		t.Errorf("got %v, want a 400 error", err)
+	}
+	if server.Exists("/123/dir") {
+		t.Errorf("directory created from a malformed path")
+	}
+}
+
+func TestFaults(t *testing.T) {
+	server := NewServer("keyname", "secret")
+	defer server.Close()
+	server.PutFile("/123/file", []byte("data"), time.Now())
+
+	server.InjectFault(Fault{Action: "stat", Status: 503, Times: 2})
+	client := server.Client("123", netstorage.WithRetry(netstorage.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	if _, err := client.Statistics("file"); err != nil {
+		t.Errorf("stat with retries: %s", err)
+	}
+	if n := len(server.Requests()); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+
+	server.InjectFault(Fault{Path: "/123/slow", Latency: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.MakeDirContext(ctx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow request: got %v", err)
+	}
+	server.ClearFaults()
+
+	server.InjectFault(Fault{Action: "upload", Status: 409})
+	if err := client.Upload("file", strings.NewReader("x"), ""); !errors.Is(err, netstorage.ErrConflict) {
+		t.Errorf("upload: got %v, want ErrConflict", err)
+	}
+}
+
+func TestZipMembers(t *testing.T) {
+	server := NewServer("keyname", "secret")
+	defer server.Close()
+	client := server.Client("123")
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "a"), 0755)
+	os.WriteFile(filepath.Join(dir, "a", "b.txt"), []byte("member"), 0644)
+	if err := client.UploadZipDir(ctx, "site.zip", dir, nil); err != nil {
+		t.Fatalf("upload: %s", err)
+	}
+	body, _, err := client.DownloadZipMember(ctx, "site.zip", "a/b.txt")
+	if err != nil {
+		t.Fatalf("download: %s", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "member" {
+		t.Errorf("got %q", data)
+	}
+	if _, _, err := client.DownloadZipMember(ctx, "site.zip", "missing"); !errors.Is(err, netstorage.ErrNotFound) {
+		t.Errorf("missing member: expected ErrNotFound, got %v", err)
+	}
+	if err := client.UploadZip(ctx, "bad.zip", strings.NewReader("not a zip"), nil); err == nil {
+		t.Error("expected an error indexing a file that is not a zip archive")
+	}
+}
+
+func TestJSONListings(t *testing.T) {
+	server := NewServer("keyname", "secret")
+	defer server.Close()
+	client := server.Client("123", netstorage.WithDecoder(netstorage.NewJSONDecoder()))
+
+	server.PutFile("/123/a/b.txt", []byte("hello"), time.Unix(1136214245, 0))
+	stat, err := client.Statistics("a/b.txt")
+	if err != nil || len(stat.Files) != 1 || stat.Files[0].Size != 5 || stat.Files[0].Mtime != 1136214245 {
+		t.Errorf("stat: got %+v, %v", stat, err)
+	}
+	dir, err := client.Dir("a")
+	if err != nil || len(dir.Files) != 1 || dir.Files[0].Name != "b.txt" {
+		t.Errorf("dir: got %+v, %v", dir, err)
+	}
+	du, err := client.DiskUsage("")
+	if err != nil || du.Info.Files != 1 || du.Info.Bytes != 5 {
+		t.Errorf("du: got %+v, %v", du, err)
+	}
+}
@@ -0,0 +1,33 @@
+// netstorage project options.go
+package netstorage
+
+import (
+	"net/http"
+	"time"
+)
+
+// An Option configures a NetstorageClient created by NewClient.
+type Option func(*NetstorageClient)
+
+// WithHTTPClient makes the client send its requests through hc instead of
+// http.DefaultClient. Use it to supply a proxy or TLS configured transport.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(client *NetstorageClient) {
+		client.httpClient = hc
+	}
+}
+
+// WithTimeout limits the time a single request, including reading the
+// response body, may take. A zero duration means no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(client *NetstorageClient) {
+		client.timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(client *NetstorageClient) {
+		client.userAgent = userAgent
+	}
+}
@@ -0,0 +1,153 @@
+// netstorage project progress.go
+package netstorage
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Progress describes how far an upload or download has come.
+type Progress struct {
+	Action      string // "upload" or "download"
+	Path        string // relative to the client's folder
+	Transferred int64  // bytes sent or received so far
+	Total       int64  // size of the transfer, -1 if unknown
+	Elapsed     time.Duration
+	Rate        float64       // average bytes per second
+	ETA         time.Duration // estimated time remaining, -1 if unknown
+}
+
+// A ProgressFunc receives progress reports. It is called from the goroutine
+// doing the transfer, so it must be fast, and it may be called concurrently
+// for transfers running in parallel.
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+// progressInterval is the least time between two reports of a transfer.
+var progressInterval = 250 * time.Millisecond
+
+// ContextWithProgress returns a context that makes the uploads and downloads
+// made with it report their progress to fn, at most a few times per second
+// and once more when the transfer ends. A retried upload starts over from
+// zero.
+func ContextWithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFor(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// progressReader reports the bytes read through it.
+type progressReader struct {
+	io.ReadCloser
+	fn       ProgressFunc
+	progress Progress
+	start    time.Time
+	last     time.Time
+	done     bool
+}
+
+// trackProgress wraps body to report its progress to the ProgressFunc of
+// req's context, if there is one.
+func (client *NetstorageClient) trackProgress(req *http.Request, body io.ReadCloser, total int64) io.ReadCloser {
+	fn := progressFor(req.Context())
+	if fn == nil || body == nil || body == http.NoBody {
+		return body
+	}
+	name, _ := url.PathUnescape(client.signedPath(req))
+	now := time.Now()
+	return &progressReader{
+		ReadCloser: body,
+		fn:         fn,
+		progress:   Progress{Action: requestAction(req), Path: client.relative(name), Total: total, ETA: -1},
+		start:      now,
+		last:       now,
+	}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.ReadCloser.Read(b)
+	p.progress.Transferred += int64(n)
+	if err == io.EOF {
+		p.report(true)
+	} else if n > 0 && time.Since(p.last) >= progressInterval {
+		p.report(false)
+	}
+	return n, err
+}
+
+func (p *progressReader) Close() error {
+	p.report(true)
+	return p.ReadCloser.Close()
+}
+
+func (p *progressReader) report(final bool) {
+	if p.done {
+		return
+	}
+	p.done = final
+	p.last = time.Now()
+	p.progress.Elapsed = p.last.Sub(p.start)
+	if secs := p.progress.Elapsed.Seconds(); secs > 0 {
+		p.progress.Rate = float64(p.progress.Transferred) / secs
+	}
+	p.progress.ETA = -1
+	if final && p.progress.Transferred == p.progress.Total {
+		p.progress.ETA = 0
+	} else if p.progress.Total >= 0 && p.progress.Rate > 0 {
+		remaining := float64(max(p.progress.Total-p.progress.Transferred, 0))
+		p.progress.ETA = time.Duration(remaining / p.progress.Rate * float64(time.Second))
+	}
+	p.fn(p.progress)
+}
+
+// seekerSize returns the number of bytes left to read from r if it is an
+// io.Seeker, such as an *os.File, or -1.
+func seekerSize(r io.Reader) int64 {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return -1
+	}
+	cur, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	if _, err := seeker.Seek(cur, io.SeekStart); err != nil {
+		return -1
+	}
+	return max(end-cur, 0)
+}
+
+// UploadFile uploads the local file localPath to remotePath, sending its
+// size and modification time along with it and a content type derived from
+// its extension. Use ContextWithProgress to follow the upload.
+func (client *NetstorageClient) UploadFile(ctx context.Context, localPath, remotePath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	opts := &UploadOptions{
+		ContentType: mime.TypeByExtension(filepath.Ext(localPath)),
+		Size:        info.Size(),
+		Mtime:       info.ModTime(),
+	}
+	return client.UploadWithOptions(ctx, remotePath, f, opts)
+}
@@ -0,0 +1,137 @@
+// netstorage project progress_test.go
+package netstorage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadFileProgress(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := strings.Repeat("x", 100000)
+	name := filepath.Join(t.TempDir(), "page.html")
+	os.WriteFile(name, []byte(data), 0644)
+	mtime := time.Unix(1136214245, 0)
+	os.Chtimes(name, mtime, mtime)
+
+	mux.HandleFunc("/base/dir/page.html",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkAction(t, r, "version=1&action=upload&mtime=1136214245&size=100000")
+			if r.ContentLength != 100000 {
+				t.Errorf("Content-Length: got %d expected 100000", r.ContentLength)
+			}
+			if got := r.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+				t.Errorf("Content-Type: got %q", got)
+			}
+			io.Copy(io.Discard, r.Body)
+		},
+	)
+	var reports []Progress
+	ctx := ContextWithProgress(context.Background(), func(p Progress) { reports = append(reports, p) })
+	if err := client.UploadFile(ctx, name, "dir/page.html"); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if len(reports) == 0 {
+		t.Fatal("no progress reported")
+	}
+	last := reports[len(reports)-1]
+	if last.Action != "upload" || last.Path != "dir/page.html" || last.Transferred != 100000 || last.Total != 100000 || last.ETA != 0 {
+		t.Errorf("unexpected final report %+v", last)
+	}
+}
+
+func TestChunkedUploadProgress(t *testing.T) {
+	setup()
+	defer teardown()
+	defer func(interval time.Duration) { progressInterval = interval }(progressInterval)
+	progressInterval = 0
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+		},
+	)
+	var reports []Progress
+	ctx := ContextWithProgress(context.Background(), func(p Progress) { reports = append(reports, p) })
+	body := io.MultiReader(strings.NewReader("hello"), strings.NewReader("world"))
+	if err := client.UploadContext(ctx, "file.txt", body, ""); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if len(reports) < 2 {
+		t.Fatalf("expected at least 2 reports, got %+v", reports)
+	}
+	first, last := reports[0], reports[len(reports)-1]
+	if first.Transferred != 5 || first.Total != -1 || first.ETA != -1 {
+		t.Errorf("unexpected first report %+v", first)
+	}
+	if last.Transferred != 10 || last.Total != -1 {
+		t.Errorf("unexpected final report %+v", last)
+	}
+}
+
+func TestDownloadProgress(t *testing.T) {
+	setup()
+	defer teardown()
+	defer func(interval time.Duration) { progressInterval = interval }(progressInterval)
+	progressInterval = 0
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "10")
+			io.WriteString(w, "hello")
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+			io.WriteString(w, "world")
+		},
+	)
+	var reports []Progress
+	ctx := ContextWithProgress(context.Background(), func(p Progress) { reports = append(reports, p) })
+	body, _, err := client.DownloadStream(ctx, "file.txt")
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	io.ReadAll(body)
+	body.Close()
+	if len(reports) < 2 {
+		t.Fatalf("expected at least 2 reports, got %+v", reports)
+	}
+	first, last := reports[0], reports[len(reports)-1]
+	if first.Transferred != 5 || first.Total != 10 || first.ETA < 0 {
+		t.Errorf("unexpected first report %+v", first)
+	}
+	if last.Action != "download" || last.Transferred != 10 || last.ETA != 0 || last.Rate <= 0 {
+		t.Errorf("unexpected final report %+v", last)
+	}
+}
+
+func TestSeekerContentLength(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength != 3 {
+				t.Errorf("Content-Length: got %d expected 3", r.ContentLength)
+			}
+		},
+	)
+	name := filepath.Join(t.TempDir(), "file.txt")
+	os.WriteFile(name, []byte("hello"), 0644)
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Seek(2, io.SeekStart)
+	if err := client.Upload("file.txt", f, ""); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
@@ -0,0 +1,173 @@
+// netstorage project ratelimit.go
+package netstorage
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultWeights are the token costs a Limiter charges for actions that are
+// more expensive for NetStorage than a stat. Other actions cost one token.
+var DefaultWeights = map[string]float64{
+	"dir":          2,
+	"list":         2,
+	"du":           5,
+	"quick-delete": 10,
+}
+
+// A Limiter throttles the requests of one or more clients with a token bucket
+// and caps the number of requests in flight. A request is in flight until
+// its response body has been closed; the upload of a Copy shares the slot of
+// the download it reads from. A Limiter is safe for concurrent use and is
+// meant to be shared by all clients of an upload account.
+type Limiter struct {
+	rate     float64 // tokens added per second, zero for no rate limit
+	burst    float64
+	inFlight chan struct{}
+
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	weights map[string]float64
+	stats   LimiterStats
+}
+
+// LimiterStats are the counters of a Limiter.
+type LimiterStats struct {
+	Requests int64         // requests that passed the limiter
+	Waited   int64         // requests that had to wait
+	WaitTime time.Duration // total time spent waiting
+	MaxWait  time.Duration // longest single wait
+	InFlight int           // requests currently in flight
+}
+
+// NewLimiter returns a Limiter allowing rate tokens per second with bursts of
+// up to burst tokens, and at most maxInFlight concurrent requests. A rate or
+// maxInFlight of zero disables that limit. Actions are weighted with
+// DefaultWeights.
+func NewLimiter(rate float64, burst int, maxInFlight int) *Limiter {
+	l := &Limiter{
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+		weights: make(map[string]float64),
+	}
+	l.tokens = l.burst
+	if maxInFlight > 0 {
+		l.inFlight = make(chan struct{}, maxInFlight)
+	}
+	for action, weight := range DefaultWeights {
+		l.weights[action] = weight
+	}
+	return l
+}
+
+// SetWeight sets the number of tokens a request for action costs.
+func (l *Limiter) SetWeight(action string, weight float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.weights[action] = weight
+}
+
+// Stats returns a snapshot of the limiter's counters.
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := l.stats
+	stats.InFlight = len(l.inFlight)
+	return stats
+}
+
+// heldSlotKey marks a context whose requests are made while the caller
+// already holds an in-flight slot that they depend on.
+type heldSlotKey struct{}
+
+// WithLimiter throttles the client's requests with l.
+func WithLimiter(l *Limiter) Option {
+	return func(client *NetstorageClient) {
+		client.limiter = l
+	}
+}
+
+// acquire waits until a request for action may be sent. The returned
+// function must be called once the request is finished. A nil Limiter
+// does not limit anything.
+func (l *Limiter) acquire(ctx context.Context, action string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	start := time.Now()
+	slot := l.inFlight != nil && ctx.Value(heldSlotKey{}) == nil
+	if slot {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if slot {
+			<-l.inFlight
+		}
+	}
+
+	weight, delay := l.reserve(action, start)
+	if delay > 0 {
+		if err := sleep(ctx, delay); err != nil {
+			l.mu.Lock()
+			l.tokens += weight
+			l.mu.Unlock()
+			release()
+			return nil, err
+		}
+	}
+
+	waited := time.Since(start)
+	l.mu.Lock()
+	l.stats.Requests++
+	if waited > time.Millisecond {
+		l.stats.Waited++
+		l.stats.WaitTime += waited
+		l.stats.MaxWait = max(l.stats.MaxWait, waited)
+	}
+	l.mu.Unlock()
+	return sync.OnceFunc(release), nil
+}
+
+// reserve takes the tokens for action from the bucket, going into debt if
+// there are not enough, and returns how long the caller has to wait for the
+// debt to be paid off.
+func (l *Limiter) reserve(action string, now time.Time) (float64, time.Duration) {
+	if l.rate <= 0 {
+		return 0, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	weight, ok := l.weights[action]
+	if !ok {
+		weight = 1
+	}
+	weight = min(weight, l.burst)
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens -= weight
+	if l.tokens >= 0 {
+		return weight, 0
+	}
+	return weight, time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// releaseBody calls release when the response body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
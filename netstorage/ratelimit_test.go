@@ -0,0 +1,101 @@
+// netstorage project ratelimit_test.go
+package netstorage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLimiterRate(t *testing.T) {
+	l := NewLimiter(100, 1, 0)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.acquire(ctx, "stat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	// the first request uses the burst, the other two wait 10ms each
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("3 requests at 100/s took only %s", elapsed)
+	}
+	stats := l.Stats()
+	if stats.Requests != 3 || stats.Waited != 2 || stats.WaitTime <= 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLimiterWeights(t *testing.T) {
+	l := NewLimiter(1000, 10, 0)
+	if weight, _ := l.reserve("du", time.Now()); weight != DefaultWeights["du"] {
+		t.Errorf("du weight: got %v expected %v", weight, DefaultWeights["du"])
+	}
+	l.SetWeight("stat", 3)
+	if weight, _ := l.reserve("stat", time.Now()); weight != 3 {
+		t.Errorf("stat weight: got %v expected 3", weight)
+	}
+}
+
+func TestLimiterInFlight(t *testing.T) {
+	l := NewLimiter(0, 0, 1)
+	setup(WithLimiter(l))
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "hello")
+		},
+	)
+	body, err := client.Download("file.txt")
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if n := l.Stats().InFlight; n != 1 {
+		t.Errorf("in flight while reading: got %d expected 1", n)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.StatisticsContext(ctx, "file.txt"); err != context.DeadlineExceeded {
+		t.Errorf("second request: got %v, expected to block until the deadline", err)
+	}
+	body.Close()
+	if n := l.Stats().InFlight; n != 0 {
+		t.Errorf("in flight after close: got %d expected 0", n)
+	}
+}
+
+func TestLimiterStreamingDownload(t *testing.T) {
+	l := NewLimiter(0, 0, 1)
+	setup(WithLimiter(l))
+	defer teardown()
+
+	var copied string
+	mux.HandleFunc("/base/",
+		func(w http.ResponseWriter, r *http.Request) {
+			switch requestAction(r) {
+			case "download":
+				io.WriteString(w, "hello")
+			case "upload":
+				data, _ := io.ReadAll(r.Body)
+				copied = string(data)
+			}
+		},
+	)
+	// the upload starts while the download body is still open
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.CopyContext(ctx, "a.txt", "b.txt"); err != nil {
+		t.Fatalf("copy: %s", err)
+	}
+	if copied != "hello" {
+		t.Errorf("copied %q", copied)
+	}
+	if n := l.Stats().InFlight; n != 0 {
+		t.Errorf("in flight after copy: got %d expected 0", n)
+	}
+}
@@ -0,0 +1,304 @@
+// netstorage project resume.go
+package netstorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// DefaultChunkSize is the size of the ranges fetched by a parallel
+// ResumeDownload when ResumeOptions.ChunkSize is not set.
+const DefaultChunkSize = 64 << 20
+
+// DownloadRange downloads length bytes of a file starting at offset. A
+// negative length reads up to the end of the file. The caller must close the
+// returned body.
+func (client *NetstorageClient) DownloadRange(ctx context.Context, file string, offset, length int64) (io.ReadCloser, *DownloadInfo, error) {
+	if offset < 0 {
+		return nil, nil, fmt.Errorf("netstorage: invalid range offset %d", offset)
+	}
+	filename := path.Join(client.Folder, file)
+	req, err := client.newRequest(ctx, "GET", filename, "download", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if length < 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	} else if length > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	}
+	resp, err := client.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	info := downloadInfo(resp)
+	if resp.StatusCode == http.StatusOK && (offset > 0 || length > 0) {
+		// the server ignored the Range header, skip to the requested range
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close()
+			return nil, nil, err
+		}
+		if info.ContentLength >= 0 {
+			info.ContentLength -= offset
+		}
+		if length > 0 {
+			info.ContentLength = length
+			return struct {
+				io.Reader
+				io.Closer
+			}{io.LimitReader(resp.Body, length), resp.Body}, info, nil
+		}
+	}
+	return resp.Body, info, nil
+}
+
+// ResumeOptions controls how ResumeDownload fetches the missing data.
+type ResumeOptions struct {
+	// Parallelism is the number of ranges fetched at the same time. Values
+	// below 2 download the remainder with a single request.
+	Parallelism int
+	// ChunkSize is the size of each range when downloading in parallel.
+	ChunkSize int64
+}
+
+// ResumeDownload downloads a file to localPath, continuing from the data
+// already present in localPath + ".part" if an earlier attempt was
+// interrupted. The version of the remote file being downloaded is recorded in
+// localPath + ".part.info", and a partial file of another version is
+// discarded. Once complete, the data is checked against the md5 NetStorage
+// reports, if any, and renamed to localPath. On other errors the partial file
+// is left in place so the download can be resumed later.
+func (client *NetstorageClient) ResumeDownload(ctx context.Context, file, localPath string, opts *ResumeOptions) (*DownloadInfo, error) {
+	if opts == nil {
+		opts = &ResumeOptions{}
+	}
+	stat, err := client.StatisticsContext(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	if len(stat.Files) == 0 {
+		return nil, fmt.Errorf("netstorage: no stat information for %s", file)
+	}
+	nsfile := stat.Files[0]
+	size := nsfile.Size
+	info := &DownloadInfo{
+		ContentLength: size,
+		LastModified:  nsfile.ModTime(),
+		Md5:           nsfile.Md5,
+	}
+
+	part := localPath + ".part"
+	version := partInfo{Md5: strings.ToLower(nsfile.Md5), Mtime: nsfile.Mtime, Size: size}
+	have := resumable(part, version)
+	out, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err = out.Truncate(have); err == nil {
+		err = version.write(part)
+	}
+	if err != nil {
+		out.Close()
+		return nil, err
+	}
+
+	if opts.Parallelism > 1 {
+		err = client.fetchParallel(ctx, file, out, have, size, opts, func(written int64) error {
+			v := version
+			v.Written = &written
+			return v.write(part)
+		})
+	} else if have < size {
+		err = client.fetchRange(ctx, file, out, have, -1)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(part)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() != size {
+		return nil, fmt.Errorf("netstorage: short download of %s: got %d bytes, want %d", file, fi.Size(), size)
+	}
+	if isHexMd5(version.Md5) {
+		sum, err := fileMd5(part)
+		if err != nil {
+			return nil, err
+		}
+		if sum != version.Md5 {
+			// resuming would only produce the same data again
+			os.Remove(part)
+			os.Remove(part + ".info")
+			return nil, fmt.Errorf("netstorage: download of %s has md5 %s, want %s", file, sum, version.Md5)
+		}
+	}
+	if err = os.Rename(part, localPath); err != nil {
+		return nil, err
+	}
+	os.Remove(part + ".info")
+	os.Chtimes(localPath, info.LastModified, info.LastModified)
+	return info, nil
+}
+
+// partInfo is kept next to a partial download and records which version of
+// the remote file it holds.
+type partInfo struct {
+	Md5   string `json:"md5,omitempty"`
+	Mtime int64  `json:"mtime"`
+	Size  int64  `json:"size"`
+	// Written is the length of the data written in order at the start of
+	// the partial file. It is set while ranges are fetched in parallel,
+	// which leaves holes behind if the process dies. Otherwise all of the
+	// partial file counts.
+	Written *int64 `json:"written,omitempty"`
+}
+
+func (v partInfo) write(part string) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(part+".info", data, 0644)
+}
+
+// resumable returns the number of bytes at the start of part that can be
+// kept when downloading version, which is zero unless part was written for
+// that same version.
+func resumable(part string, version partInfo) int64 {
+	fi, err := os.Stat(part)
+	if err != nil {
+		return 0
+	}
+	data, err := os.ReadFile(part + ".info")
+	if err != nil {
+		return 0
+	}
+	var recorded partInfo
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return 0
+	}
+	if recorded.Md5 != version.Md5 || recorded.Mtime != version.Mtime || recorded.Size != version.Size {
+		return 0
+	}
+	have := min(fi.Size(), version.Size)
+	if recorded.Written != nil {
+		have = min(have, max(*recorded.Written, 0))
+	}
+	return have
+}
+
+// fetchRange writes the given range of file into out at offset.
+func (client *NetstorageClient) fetchRange(ctx context.Context, file string, out *os.File, offset, length int64) error {
+	body, _, err := client.DownloadRange(ctx, file, offset, length)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	w := io.NewOffsetWriter(out, offset)
+	if length < 0 {
+		_, err = io.Copy(w, body)
+		return err
+	}
+	_, err = io.CopyN(w, body, length)
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// fetchParallel downloads [have, size) of file in chunks using up to
+// opts.Parallelism concurrent requests. The length of the contiguous prefix
+// written so far is passed to record whenever it grows, starting with have.
+// If any chunk fails, out is truncated to that prefix, so a later call can
+// resume from there.
+func (client *NetstorageClient) fetchParallel(ctx context.Context, file string, out *os.File, have, size int64, opts *ResumeOptions, record func(written int64) error) error {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	type chunk struct{ offset, length int64 }
+	var chunks []chunk
+	for off := have; off < size; off += chunkSize {
+		chunks = append(chunks, chunk{off, min(chunkSize, size-off)})
+	}
+
+	if err := record(have); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		end      = have                  // end of the contiguous prefix
+		done     = make(map[int64]int64) // offset -> length of finished chunks past end
+		work     = make(chan chunk)
+	)
+	for i := 0; i < opts.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range work {
+				err := client.fetchRange(ctx, file, out, c.offset, c.length)
+				mu.Lock()
+				if err == nil {
+					done[c.offset] = c.length
+					grown := false
+					for length, ok := done[end]; ok; length, ok = done[end] {
+						delete(done, end)
+						end += length
+						grown = true
+					}
+					if grown {
+						err = record(end)
+					}
+				}
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, c := range chunks {
+		select {
+		case work <- c:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr == nil {
+		if err := ctx.Err(); err != nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return nil
+	}
+	if err := out.Truncate(end); err != nil {
+		return err
+	}
+	return firstErr
+}
@@ -0,0 +1,147 @@
+// netstorage project resume_test.go
+package netstorage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var rangeContent = []byte(strings.Repeat("0123456789", 100))
+
+const rangeMd5 = "427008b3fe192f663d665f56cd75716c"
+
+// handleRangeFile serves big.bin with the given md5 and mtime and returns
+// the Range headers of the downloads.
+func handleRangeFile(t *testing.T, md5 string, mtime int64) *[]string {
+	var ranges []string
+	mux.HandleFunc("/base/big.bin",
+		func(w http.ResponseWriter, r *http.Request) {
+			switch action := r.Header.Get("X-Akamai-ACS-Action"); action {
+			case "version=1&action=stat&format=xml":
+				fmt.Fprintf(w, `<stat directory="/base"><file type="file" name="big.bin" size="%d" md5="%s" mtime="%d"/></stat>`, len(rangeContent), md5, mtime)
+			case "version=1&action=download":
+				ranges = append(ranges, r.Header.Get("Range"))
+				http.ServeContent(w, r, "big.bin", time.Time{}, bytes.NewReader(rangeContent))
+			default:
+				t.Errorf("unexpected action %q", action)
+			}
+		},
+	)
+	return &ranges
+}
+
+func TestDownloadRange(t *testing.T) {
+	setup()
+	defer teardown()
+	handleRangeFile(t, rangeMd5, 1136214245)
+
+	body, _, err := client.DownloadRange(context.Background(), "big.bin", 10, 5)
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	if string(data) != "01234" {
+		t.Errorf("got %q expected %q", data, "01234")
+	}
+}
+
+func TestResumeDownload(t *testing.T) {
+	setup()
+	defer teardown()
+	ranges := handleRangeFile(t, rangeMd5, 1136214245)
+
+	local := filepath.Join(t.TempDir(), "big.bin")
+	version := partInfo{Md5: rangeMd5, Mtime: 1136214245, Size: int64(len(rangeContent))}
+	for _, opts := range []*ResumeOptions{nil, {Parallelism: 4, ChunkSize: 100}} {
+		// an earlier, interrupted attempt
+		if err := os.WriteFile(local+".part", rangeContent[:333], 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := version.write(local + ".part"); err != nil {
+			t.Fatal(err)
+		}
+		*ranges = nil
+		if _, err := client.ResumeDownload(context.Background(), "big.bin", local, opts); err != nil {
+			t.Fatalf("API error: %s", err)
+		}
+		data, err := os.ReadFile(local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, rangeContent) {
+			t.Errorf("downloaded content differs (opts %+v)", opts)
+		}
+		if len(*ranges) == 0 || !strings.HasPrefix((*ranges)[0], "bytes=333-") {
+			t.Errorf("did not resume at 333 (opts %+v): %q", opts, *ranges)
+		}
+		for _, leftover := range []string{".part", ".part.info"} {
+			if _, err := os.Stat(local + leftover); !os.IsNotExist(err) {
+				t.Errorf("%s file left behind", leftover)
+			}
+		}
+	}
+}
+
+func TestResumeDownloadStalePart(t *testing.T) {
+	setup()
+	defer teardown()
+	ranges := handleRangeFile(t, rangeMd5, 1136214245)
+
+	local := filepath.Join(t.TempDir(), "big.bin")
+	stale := bytes.Repeat([]byte("x"), 500)
+	written := int64(200)
+	for _, recorded := range []*partInfo{
+		nil, // no record of the version
+		{Md5: "00000000000000000000000000000000", Mtime: 1136214245, Size: 1000}, // an older version
+		{Md5: rangeMd5, Mtime: 1136214245, Size: 1000, Written: &written},        // killed parallel download
+	} {
+		os.Remove(local + ".part.info")
+		if err := os.WriteFile(local+".part", append(rangeContent[:written:written], stale...), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if recorded != nil {
+			if err := recorded.write(local + ".part"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		*ranges = nil
+		if _, err := client.ResumeDownload(context.Background(), "big.bin", local, nil); err != nil {
+			t.Fatalf("API error: %s", err)
+		}
+		if data, _ := os.ReadFile(local); !bytes.Equal(data, rangeContent) {
+			t.Errorf("stale data kept (recorded %+v)", recorded)
+		}
+		want := "bytes=0-"
+		if recorded != nil && recorded.Written != nil {
+			want = "bytes=200-"
+		}
+		if len(*ranges) != 1 || (*ranges)[0] != want {
+			t.Errorf("got ranges %q, expected %s (recorded %+v)", *ranges, want, recorded)
+		}
+	}
+}
+
+func TestResumeDownloadMd5Mismatch(t *testing.T) {
+	setup()
+	defer teardown()
+	handleRangeFile(t, "0123456789abcdef0123456789abcdef", 1136214245)
+
+	local := filepath.Join(t.TempDir(), "big.bin")
+	if _, err := client.ResumeDownload(context.Background(), "big.bin", local, nil); err == nil || !strings.Contains(err.Error(), "md5") {
+		t.Fatalf("expected an md5 error, got %v", err)
+	}
+	for _, name := range []string{local, local + ".part", local + ".part.info"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s left behind", name)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// netstorage project retry.go
+package netstorage
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A RetryPolicy says how often and how long after a failed request it is
+// sent again. Requests are retried after network errors and 429, 500, 502,
+// 503 and 504 responses. Actions that are not idempotent (rename and
+// quick-delete) are only retried after a 429, and uploads only when their
+// data can be rewound, i.e. it is an io.Seeker. Each attempt is signed anew.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent at most,
+	// including the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It is doubled for
+	// every further attempt, and a random jitter of up to half the delay is
+	// taken off.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts. A Retry-After header sent
+	// by NetStorage is honored even if it is longer.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is a reasonable policy for WithRetry.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   250 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// WithRetry makes the client retry failed requests as described by policy.
+// By default requests are not retried.
+func WithRetry(policy RetryPolicy) Option {
+	return func(client *NetstorageClient) {
+		client.retry = &policy
+	}
+}
+
+// backoff reports whether the attempt that produced resp or err should be
+// retried and how long to wait before doing so.
+func (p *RetryPolicy) backoff(attempt int, req *http.Request, resp *http.Response, err error) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if !resendable(req) {
+		// the data has been consumed and can't be sent again
+		return 0, false
+	}
+	if err != nil {
+		if req.Context().Err() != nil || !idempotent(req) {
+			return 0, false
+		}
+	} else {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests:
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			if !idempotent(req) {
+				return 0, false
+			}
+		default:
+			return 0, false
+		}
+	}
+
+	delay := p.delay(attempt)
+	if resp != nil {
+		if after, ok := retryAfter(resp); ok {
+			delay = after
+		}
+	}
+	return delay, true
+}
+
+// delay returns the jittered exponential delay after the given attempt.
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay > 1 {
+		delay -= time.Duration(rand.Int63n(int64(delay / 2)))
+	}
+	return delay
+}
+
+// resendable reports whether the body of req, if any, can be rewound.
+func resendable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func idempotent(req *http.Request) bool {
+	switch requestAction(req) {
+	case "rename", "quick-delete":
+		return false
+	}
+	return true
+}
+
+// retryAfter parses the Retry-After header, given in seconds or as a date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rewindable makes req resendable if body is an io.Seeker that
+// http.NewRequest does not know how to rewind itself.
+func rewindable(req *http.Request, body io.Reader) {
+	seeker, ok := body.(io.ReadSeeker)
+	if !ok || req.GetBody != nil {
+		return
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	// keep the transport from closing bodies like *os.File between attempts
+	req.Body = io.NopCloser(seeker)
+	req.GetBody = func() (io.ReadCloser, error) {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(seeker), nil
+	}
+}
+
+// resign returns a copy of req for another attempt, with a rewound body and
+// signed with a fresh timestamp and a request id derived from id, the one of
+// the first attempt.
+func (client *NetstorageClient) resign(req *http.Request, id string, attempt int) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	filename := client.signedPath(req)
+	action := strings.TrimPrefix(req.Header.Get("X-Akamai-ACS-Action"), "version=1&action=")
+	client.auth(retry, retryRequestID(req.Context(), id, attempt), filename, client.now().Unix(), action)
+	return retry, nil
+}
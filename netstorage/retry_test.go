@@ -0,0 +1,92 @@
+// netstorage project retry_test.go
+package netstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestRetry(t *testing.T) {
+	var ids []string
+	setup(WithRetry(fastRetry))
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			ids = append(ids, requestIDOf(r))
+			if data, _ := io.ReadAll(r.Body); string(data) != "hello" {
+				t.Errorf("attempt %d: body %q", len(ids), data)
+			}
+			if len(ids) < 3 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+			}
+		},
+	)
+	// an *os.File is rewound between attempts
+	name := filepath.Join(t.TempDir(), "file.txt")
+	os.WriteFile(name, []byte("hello"), 0644)
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := client.Upload("file.txt", f, "text/plain"); err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	if len(ids) != 3 || ids[0] == ids[1] || ids[1] == ids[2] {
+		t.Errorf("expected 3 attempts signed with different ids, got %v", ids)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var attempts int
+	setup(WithRetry(fastRetry))
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		},
+	)
+	if _, err := client.Statistics("file.txt"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("got %v, want ErrRateLimited", err)
+	}
+	if attempts != fastRetry.MaxAttempts {
+		t.Errorf("got %d attempts, want %d", attempts, fastRetry.MaxAttempts)
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	var attempts int
+	setup(WithRetry(fastRetry))
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			http.Error(w, "oops", http.StatusInternalServerError)
+		},
+	)
+	// rename is not idempotent
+	client.Rename("file.txt", "other.txt")
+	if attempts != 1 {
+		t.Errorf("rename: got %d attempts, want 1", attempts)
+	}
+	// a plain reader can't be sent again
+	attempts = 0
+	client.UploadContext(context.Background(), "file.txt", io.MultiReader(strings.NewReader("hello")), "")
+	if attempts != 1 {
+		t.Errorf("upload: got %d attempts, want 1", attempts)
+	}
+}
@@ -0,0 +1,85 @@
+// netstorage project sign_test.go
+package netstorage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSignature(t *testing.T) {
+	clock := func() time.Time { return time.Unix(1136214245, 0) }
+	setup(WithClock(clock), WithSignVersion(SignHMACSHA1))
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			authData := "4, 0.0.0.0, 0.0.0.0, 1136214245, req-1, keyname"
+			if got := r.Header.Get("X-Akamai-ACS-Auth-Data"); got != authData {
+				t.Errorf("X-Akamai-ACS-Auth-Data: got %q expected %q", got, authData)
+			}
+			mac := hmac.New(sha1.New, []byte("secret"))
+			fmt.Fprintf(mac, "%s/base/file.txt\nx-akamai-acs-action:version=1&action=download\n", authData)
+			if got, sign := r.Header.Get("X-Akamai-ACS-Auth-Sign"), base64.StdEncoding.EncodeToString(mac.Sum(nil)); got != sign {
+				t.Errorf("X-Akamai-ACS-Auth-Sign: got %q expected %q", got, sign)
+			}
+			io.WriteString(w, "hello")
+		},
+	)
+	body, info, err := client.DownloadStream(ContextWithRequestID(context.Background(), "req-1"), "file.txt")
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	body.Close()
+	if info.RequestID != "req-1" {
+		t.Errorf("RequestID: got %q expected %q", info.RequestID, "req-1")
+	}
+}
+
+func TestUniqueRequestIDs(t *testing.T) {
+	var ids []string
+	setup(WithRetry(fastRetry))
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			ids = append(ids, requestIDOf(r))
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		},
+	)
+	client.Delete("file.txt")
+	client.Delete("file.txt")
+	if len(ids) != 6 || ids[0] == ids[3] {
+		t.Errorf("expected 6 requests with random ids, got %v", ids)
+	}
+
+	ids = nil
+	client.DeleteContext(ContextWithRequestID(context.Background(), "job-7"), "file.txt")
+	if fmt.Sprint(ids) != "[job-7 job-7-2 job-7-3]" {
+		t.Errorf("got ids %v", ids)
+	}
+
+	// every request made with the context gets an id of its own
+	ids = nil
+	ctx := ContextWithRequestID(context.Background(), "job-8")
+	client.DeleteContext(ctx, "file.txt")
+	client.DeleteContext(ctx, "file.txt")
+	if fmt.Sprint(ids) != "[job-8 job-8-2 job-8-3 job-8.2 job-8.2-2 job-8.2-3]" {
+		t.Errorf("got ids %v", ids)
+	}
+}
+
+func TestUnsupportedSignVersion(t *testing.T) {
+	setup(WithSignVersion(2))
+	defer teardown()
+
+	if err := client.Delete("file.txt"); err == nil {
+		t.Errorf("expected an error for signature version 2")
+	}
+}
@@ -0,0 +1,277 @@
+// netstorage project sync.go
+package netstorage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyncOptions controls what Sync transfers.
+type SyncOptions struct {
+	// DryRun only computes the plan without changing anything.
+	DryRun bool
+	// Exclude lists path.Match patterns. A file or directory is skipped if
+	// a pattern matches its slash separated path relative to the synced
+	// directories or its base name. Excluded remote files are never deleted.
+	Exclude []string
+	// Delete removes remote files that do not exist locally.
+	Delete bool
+	// Concurrency is the number of transfers run at the same time.
+	Concurrency int
+}
+
+func (opts *SyncOptions) excluded(rel string) bool {
+	for _, pattern := range opts.Exclude {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// A SyncAction is what Sync does to bring a remote file up to date.
+type SyncAction string
+
+const (
+	SyncUpload SyncAction = "upload"
+	SyncDelete SyncAction = "delete"
+)
+
+// A SyncItem is a single step of a SyncPlan.
+type SyncItem struct {
+	Action SyncAction
+	Path   string // slash separated and relative to the synced directories
+	Reason string // why the action is needed, e.g. "size differs"
+	Size   int64  // size of the local file for uploads
+	Err    error  // set if the action failed
+}
+
+// A SyncPlan lists the actions Sync took, or would take in a dry run.
+type SyncPlan struct {
+	Items []SyncItem
+}
+
+// Bytes returns the number of bytes the plan uploads.
+func (p *SyncPlan) Bytes() int64 {
+	var n int64
+	for _, item := range p.Items {
+		if item.Action == SyncUpload {
+			n += item.Size
+		}
+	}
+	return n
+}
+
+// Failed returns the items whose action failed.
+func (p *SyncPlan) Failed() []SyncItem {
+	var failed []SyncItem
+	for _, item := range p.Items {
+		if item.Err != nil {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+type localFile struct {
+	path  string
+	size  int64
+	mtime time.Time
+}
+
+// Sync makes remoteDir mirror the regular files in localDir. Files missing
+// remotely are uploaded, as are files whose size differs, whose md5 differs
+// when NetStorage reports one, or otherwise whose modification time differs.
+// Uploaded files keep their local modification time. The returned plan holds
+// the outcome of every action; if any failed the error joins their errors.
+func (client *NetstorageClient) Sync(ctx context.Context, localDir, remoteDir string, opts *SyncOptions) (*SyncPlan, error) {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	locals, err := listLocal(localDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	remotes, err := client.listRemote(ctx, remoteDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &SyncPlan{}
+	for rel, local := range locals {
+		reason, err := syncReason(local, remotes[rel])
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			plan.Items = append(plan.Items, SyncItem{Action: SyncUpload, Path: rel, Reason: reason, Size: local.size})
+		}
+	}
+	if opts.Delete {
+		for rel := range remotes {
+			if _, ok := locals[rel]; !ok {
+				plan.Items = append(plan.Items, SyncItem{Action: SyncDelete, Path: rel, Reason: "not present locally"})
+			}
+		}
+	}
+	sort.Slice(plan.Items, func(i, j int) bool { return plan.Items[i].Path < plan.Items[j].Path })
+	if opts.DryRun {
+		return plan, nil
+	}
+
+	concurrency := max(opts.Concurrency, 1)
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			item.Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			remote := path.Join(remoteDir, item.Path)
+			if item.Action == SyncDelete {
+				item.Err = client.DeleteContext(ctx, remote)
+			} else {
+				item.Err = client.syncUpload(ctx, locals[item.Path], remote)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, item := range plan.Failed() {
+		errs = append(errs, fmt.Errorf("%s %s: %w", item.Action, item.Path, item.Err))
+	}
+	return plan, errors.Join(errs...)
+}
+
+func (client *NetstorageClient) syncUpload(ctx context.Context, local localFile, remote string) error {
+	f, err := os.Open(local.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return client.UploadWithOptions(ctx, remote, f, &UploadOptions{Mtime: local.mtime, ComputeChecksums: true})
+}
+
+// syncReason says why local has to be uploaded over remote, or returns ""
+// if the remote copy is up to date.
+func syncReason(local localFile, remote *NSFile) (string, error) {
+	switch {
+	case remote == nil:
+		return "missing remotely", nil
+	case remote.Size != local.size:
+		return "size differs", nil
+	case remote.Md5 != "":
+		sum, err := fileMd5(local.path)
+		if err != nil {
+			return "", err
+		}
+		if !strings.EqualFold(sum, remote.Md5) {
+			return "md5 differs", nil
+		}
+	case remote.Mtime != local.mtime.Unix():
+		return "mtime differs", nil
+	}
+	return "", nil
+}
+
+func fileMd5(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := md5.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func listLocal(localDir string, opts *SyncOptions) (map[string]localFile, error) {
+	locals := make(map[string]localFile)
+	err := filepath.WalkDir(localDir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if opts.excluded(rel) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		locals[rel] = localFile{path: name, size: info.Size(), mtime: info.ModTime()}
+		return nil
+	})
+	return locals, err
+}
+
+// listRemote returns the files below remoteDir by their relative path. A
+// missing remoteDir is treated as empty.
+func (client *NetstorageClient) listRemote(ctx context.Context, remoteDir string, opts *SyncOptions) (map[string]*NSFile, error) {
+	remotes := make(map[string]*NSFile)
+	root := path.Clean(remoteDir)
+	err := client.Walk(ctx, root, func(name string, file NSFile, err error) error {
+		if err != nil {
+			if name == root && errors.Is(err, ErrNotFound) {
+				return SkipAll
+			}
+			return err
+		}
+		if name == root {
+			return nil
+		}
+		rel := name
+		if root != "." {
+			rel = strings.TrimPrefix(name, root+"/")
+		}
+		if opts.excluded(rel) {
+			if file.IsDir() {
+				return SkipDir
+			}
+			return nil
+		}
+		if file.Type == "file" {
+			remotes[rel] = &file
+		}
+		return nil
+	})
+	return remotes, err
+}
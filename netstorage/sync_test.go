@@ -0,0 +1,91 @@
+// netstorage project sync_test.go
+package netstorage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		uploaded []string
+		deleted  []string
+	)
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/site",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkAction(t, r, "version=1&action=dir&format=xml")
+			fmt.Fprint(w, `<stat directory="/base/site">`+
+				`<file type="file" name="same.txt" size="4" md5="51037a4a37730f52c8732586d3aaa316" mtime="1"/>`+
+				`<file type="file" name="changed.txt" size="1" mtime="1"/>`+
+				`<file type="file" name="old.txt" size="1" mtime="1"/>`+
+				`<file type="file" name="keep.tmp" size="1" mtime="1"/>`+
+				`</stat>`)
+		},
+	)
+	mux.HandleFunc("/base/site/",
+		func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch r.Method {
+			case "PUT":
+				uploaded = append(uploaded, r.URL.Path)
+			case "DELETE":
+				deleted = append(deleted, r.URL.Path)
+			default:
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}
+		},
+	)
+
+	local := t.TempDir()
+	for name, content := range map[string]string{
+		"same.txt":    "same",
+		"changed.txt": "changed",
+		"new.txt":     "new",
+		"skip.tmp":    "skip",
+	} {
+		if err := os.WriteFile(filepath.Join(local, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := &SyncOptions{Delete: true, Exclude: []string{"*.tmp"}, Concurrency: 2, DryRun: true}
+	plan, err := client.Sync(context.Background(), local, "site", opts)
+	if err != nil {
+		t.Fatalf("sync error: %s", err)
+	}
+	expected := []SyncItem{
+		{Action: SyncUpload, Path: "changed.txt", Reason: "size differs", Size: 7},
+		{Action: SyncUpload, Path: "new.txt", Reason: "missing remotely", Size: 3},
+		{Action: SyncDelete, Path: "old.txt", Reason: "not present locally"},
+	}
+	if !reflect.DeepEqual(plan.Items, expected) {
+		t.Errorf("got %+v expected %+v", plan.Items, expected)
+	}
+	if len(uploaded)+len(deleted) != 0 {
+		t.Errorf("dry run changed files: %v %v", uploaded, deleted)
+	}
+
+	opts.DryRun = false
+	if _, err = client.Sync(context.Background(), local, "site", opts); err != nil {
+		t.Fatalf("sync error: %s", err)
+	}
+	sort.Strings(uploaded)
+	if !reflect.DeepEqual(uploaded, []string{"/base/site/changed.txt", "/base/site/new.txt"}) {
+		t.Errorf("uploaded %v", uploaded)
+	}
+	if !reflect.DeepEqual(deleted, []string{"/base/site/old.txt"}) {
+		t.Errorf("deleted %v", deleted)
+	}
+}
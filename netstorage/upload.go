@@ -0,0 +1,206 @@
+// netstorage project upload.go
+package netstorage
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UploadOptions are the optional parameters of an upload. NetStorage rejects
+// the upload if the received data does not match any checksum or size given.
+type UploadOptions struct {
+	ContentType string
+	Md5         string // hex encoded
+	Sha1        string // hex encoded
+	Sha256      string // hex encoded
+	Size        int64  // sent when greater than zero
+	Mtime       time.Time
+	// IndexZip asks NetStorage to index the uploaded zip archive so that
+	// its members can be served individually, see UploadZip.
+	IndexZip bool
+
+	// ComputeChecksums computes the size and the md5, sha1 and sha256
+	// checksums of the data for any of them not set explicitly. Data that is
+	// not an io.ReadSeeker is spooled to a temporary file while hashing.
+	ComputeChecksums bool
+}
+
+func (opts *UploadOptions) verifies() bool {
+	return opts.Md5 != "" || opts.Sha1 != "" || opts.Sha256 != "" || opts.Size > 0
+}
+
+func (opts *UploadOptions) action() string {
+	params := url.Values{}
+	if opts.Md5 != "" {
+		params.Set("md5", opts.Md5)
+	}
+	if opts.Sha1 != "" {
+		params.Set("sha1", opts.Sha1)
+	}
+	if opts.Sha256 != "" {
+		params.Set("sha256", opts.Sha256)
+	}
+	if opts.Size > 0 {
+		params.Set("size", strconv.FormatInt(opts.Size, 10))
+	}
+	if !opts.Mtime.IsZero() {
+		params.Set("mtime", strconv.FormatInt(opts.Mtime.Unix(), 10))
+	}
+	if opts.IndexZip {
+		params.Set("index-zip", "1")
+	}
+	if len(params) == 0 {
+		return "upload"
+	}
+	return "upload&" + params.Encode()
+}
+
+// A ChecksumMismatchError is returned by UploadWithOptions when NetStorage
+// refused the data because it did not match the checksums or size sent.
+type ChecksumMismatchError struct {
+	Path string
+	Err  *NSError
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("netstorage: checksum mismatch uploading %s: %v", e.Path, e.Err)
+}
+
+func (e *ChecksumMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// UploadWithOptions uploads data to the path specified by the name, sending
+// the checksums, size and mtime from opts along with it. The Content-Length
+// is sent if the data is an io.Seeker, such as an *os.File, or its size is
+// computed; otherwise the data is sent chunked.
+func (client *NetstorageClient) UploadWithOptions(ctx context.Context, name string, r io.Reader, opts *UploadOptions) error {
+	var o UploadOptions
+	if opts != nil {
+		o = *opts
+	}
+	size := int64(-1)
+	if o.ComputeChecksums {
+		spooled, n, err := computeChecksums(r, &o)
+		if err != nil {
+			return err
+		}
+		if spooled != nil {
+			defer os.Remove(spooled.Name())
+			defer spooled.Close()
+			r = spooled
+		}
+		size = n
+	} else {
+		size = seekerSize(r)
+	}
+
+	filename := path.Join(client.Folder, name)
+	req, err := client.newRequest(ctx, "PUT", filename, o.action(), r)
+	if err != nil {
+		return err
+	}
+	if o.ContentType != "" {
+		req.Header.Set("Content-Type", o.ContentType)
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	resp, err := client.do(req)
+	if err != nil {
+		var nserr *NSError
+		if errors.As(err, &nserr) && errors.Is(err, ErrConflict) && o.verifies() && isChecksumMismatch(nserr) {
+			return &ChecksumMismatchError{Path: filename, Err: nserr}
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// isChecksumMismatch reports whether a 409 error says that the data did not
+// match the checksums or size sent. Other conflicts, such as uploading onto a
+// directory, are reported as they are.
+func isChecksumMismatch(e *NSError) bool {
+	detail := strings.ToLower(e.Detail)
+	if !strings.Contains(detail, "mismatch") {
+		return false
+	}
+	for _, word := range []string{"checksum", "md5", "sha1", "sha256", "size"} {
+		if strings.Contains(detail, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// computeChecksums fills in the checksums and size missing from opts by
+// reading r. If r is an io.ReadSeeker it is rewound afterwards, otherwise the
+// data is copied to a temporary file which is returned positioned at its start
+// and must be removed by the caller.
+func computeChecksums(r io.Reader, opts *UploadOptions) (*os.File, int64, error) {
+	md5Hash, sha1Hash, sha256Hash := md5.New(), sha1.New(), sha256.New()
+	hashes := io.MultiWriter(md5Hash, sha1Hash, sha256Hash)
+
+	var spooled *os.File
+	seeker, seekable := r.(io.ReadSeeker)
+	var start int64
+	var err error
+	if seekable {
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		if spooled, err = os.CreateTemp("", "netstorage-upload-*"); err != nil {
+			return nil, 0, err
+		}
+		hashes = io.MultiWriter(hashes, spooled)
+	}
+	cleanup := func() {
+		if spooled != nil {
+			spooled.Close()
+			os.Remove(spooled.Name())
+		}
+	}
+
+	n, err := io.Copy(hashes, r)
+	if err != nil {
+		cleanup()
+		return nil, 0, err
+	}
+	if seekable {
+		_, err = seeker.Seek(start, io.SeekStart)
+	} else {
+		_, err = spooled.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, err
+	}
+
+	if opts.Md5 == "" {
+		opts.Md5 = hex.EncodeToString(md5Hash.Sum(nil))
+	}
+	if opts.Sha1 == "" {
+		opts.Sha1 = hex.EncodeToString(sha1Hash.Sum(nil))
+	}
+	if opts.Sha256 == "" {
+		opts.Sha256 = hex.EncodeToString(sha256Hash.Sum(nil))
+	}
+	if opts.Size <= 0 {
+		opts.Size = n
+	}
+	return spooled, n, nil
+}
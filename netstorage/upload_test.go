@@ -0,0 +1,77 @@
+// netstorage project upload_test.go
+package netstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadWithOptions(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkMethod(t, r, "PUT")
+			checkAction(t, r, "version=1&action=upload&md5=5d41402abc4b2a76b9719d911017c592&mtime=1136214245"+
+				"&sha1=aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"+
+				"&sha256=2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824&size=5")
+			if got := r.Header.Get("Content-Type"); got != "text/plain" {
+				t.Errorf("Content-Type: got %q", got)
+			}
+			if r.ContentLength != 5 {
+				t.Errorf("Content-Length: got %d expected 5", r.ContentLength)
+			}
+			if data, _ := io.ReadAll(r.Body); string(data) != "hello" {
+				t.Errorf("body: got %q", data)
+			}
+		},
+	)
+	// wrapped so that the data is not seekable and has to be spooled
+	r := io.MultiReader(strings.NewReader("hello"))
+	opts := &UploadOptions{ContentType: "text/plain", Mtime: time.Unix(1136214245, 0), ComputeChecksums: true}
+	if err := client.UploadWithOptions(context.Background(), "file.txt", r, opts); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
+
+func TestUploadChecksumMismatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/file.txt",
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "md5 mismatch", http.StatusConflict)
+		},
+	)
+	err := client.UploadWithOptions(context.Background(), "file.txt", strings.NewReader("hello"), &UploadOptions{Md5: "00000000000000000000000000000000"})
+	var mismatch *ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("got %v, want a *ChecksumMismatchError", err)
+	}
+}
+
+func TestUploadConflict(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/dir",
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "target is a directory", http.StatusConflict)
+		},
+	)
+	err := client.UploadWithOptions(context.Background(), "dir", strings.NewReader("hello"), &UploadOptions{Md5: "5d41402abc4b2a76b9719d911017c592"})
+	var mismatch *ChecksumMismatchError
+	if errors.As(err, &mismatch) {
+		t.Errorf("got %v, want a plain *NSError", err)
+	}
+	var nserr *NSError
+	if !errors.As(err, &nserr) || !errors.Is(err, ErrConflict) {
+		t.Errorf("got %v, want a 409 *NSError", err)
+	}
+}
@@ -0,0 +1,193 @@
+// netstorage project walk.go
+package netstorage
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sync"
+)
+
+// SkipDir can be returned by a WalkFunc to skip the directory named in the
+// call, or the remaining entries of the directory if called for a file.
+var SkipDir = fs.SkipDir
+
+// SkipAll can be returned by a WalkFunc to stop the walk without error.
+var SkipAll = fs.SkipAll
+
+// WalkFunc is called by Walk for every file and directory visited. name is
+// the path of the entry relative to the client's folder. If listing a
+// directory fails fn is called a second time for it with the error, and
+// returning nil continues the walk with the next entry.
+type WalkFunc func(name string, file NSFile, err error) error
+
+// WalkOptions controls how Walk lists directories.
+type WalkOptions struct {
+	// Concurrency is the number of directories listed at the same time.
+	// When greater than one, directories are visited in no particular
+	// order. fn is never called concurrently either way.
+	Concurrency int
+	// PageSize is the max_entries of each dir request; zero lets the
+	// server decide.
+	PageSize int
+}
+
+// Walk walks the tree rooted at root, calling fn for root and every entry
+// below it. Directories are listed a page at a time. Entries of a directory
+// are visited in the order NetStorage returns them and, like
+// filepath.WalkDir, a directory is visited before its contents.
+func (client *NetstorageClient) Walk(ctx context.Context, root string, fn WalkFunc) error {
+	return client.WalkWithOptions(ctx, root, fn, nil)
+}
+
+// WalkWithOptions is like Walk but lists directories as configured by opts.
+func (client *NetstorageClient) WalkWithOptions(ctx context.Context, root string, fn WalkFunc, opts *WalkOptions) error {
+	if opts == nil {
+		opts = &WalkOptions{}
+	}
+	root = path.Clean(root)
+	rootFile := NSFile{Type: "dir", Name: path.Base(root)}
+	if err := fn(root, rootFile, nil); err != nil {
+		if err == SkipDir || err == SkipAll {
+			return nil
+		}
+		return err
+	}
+	w := &walker{client: client, fn: fn, pageSize: opts.PageSize}
+	var err error
+	if opts.Concurrency > 1 {
+		err = w.walkParallel(ctx, root, rootFile, opts.Concurrency)
+	} else {
+		err = w.walk(ctx, root, rootFile)
+	}
+	if err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+type walker struct {
+	client   *NetstorageClient
+	fn       WalkFunc
+	pageSize int
+}
+
+// list calls visit for every entry of dir, one page at a time. Returning
+// SkipDir from visit ends the listing early without error.
+func (w *walker) list(ctx context.Context, dir string, visit func(name string, file NSFile) error) error {
+	opts := &DirOptions{MaxEntries: w.pageSize}
+	for {
+		stat, err := w.client.DirWithOptions(ctx, dir, opts)
+		if err != nil {
+			return err
+		}
+		for _, file := range stat.Files {
+			if err := visit(path.Join(dir, file.Name), file); err != nil {
+				if err == SkipDir {
+					return nil
+				}
+				return err
+			}
+		}
+		if stat.Resume.Start == "" || stat.Resume.Start == opts.Start {
+			return nil
+		}
+		opts.Start = stat.Resume.Start
+	}
+}
+
+// listError reports a failure to list dir to fn.
+func (w *walker) listError(ctx context.Context, dir string, dirFile NSFile, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if err = w.fn(dir, dirFile, err); err == SkipDir {
+		return nil
+	}
+	return err
+}
+
+func (w *walker) walk(ctx context.Context, dir string, dirFile NSFile) error {
+	var fnErr error
+	err := w.list(ctx, dir, func(name string, file NSFile) error {
+		err := w.fn(name, file, nil)
+		if err == nil && file.IsDir() {
+			err = w.walk(ctx, name, file)
+		} else if err == SkipDir && file.IsDir() {
+			return nil
+		}
+		fnErr = err
+		return err
+	})
+	if err != nil && err != fnErr {
+		return w.listError(ctx, dir, dirFile, err)
+	}
+	return err
+}
+
+func (w *walker) walkParallel(ctx context.Context, root string, rootFile NSFile, concurrency int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	var walkDir func(dir string, dirFile NSFile)
+	walkDir = func(dir string, dirFile NSFile) {
+		defer wg.Done()
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		defer func() { <-sem }()
+
+		var fnErr error
+		err := w.list(ctx, dir, func(name string, file NSFile) error {
+			mu.Lock()
+			err := w.fn(name, file, nil)
+			mu.Unlock()
+			if err != nil {
+				if err == SkipDir && file.IsDir() {
+					return nil
+				}
+				fnErr = err
+				return err
+			}
+			if file.IsDir() {
+				wg.Add(1)
+				go walkDir(name, file)
+			}
+			return nil
+		})
+		if err != nil && err != fnErr {
+			mu.Lock()
+			err = w.listError(ctx, dir, dirFile, err)
+			mu.Unlock()
+		}
+		if err != nil {
+			fail(err)
+		}
+	}
+	wg.Add(1)
+	go walkDir(root, rootFile)
+	wg.Wait()
+
+	if firstErr == nil {
+		// the caller's context may have ended the walk early
+		return ctx.Err()
+	}
+	return firstErr
+}
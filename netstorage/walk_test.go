@@ -0,0 +1,88 @@
+// netstorage project walk_test.go
+package netstorage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func handleTree(t *testing.T) {
+	mux.HandleFunc("/base/root",
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Header.Get("X-Akamai-ACS-Action") {
+			case "version=1&action=dir&format=xml&max_entries=2":
+				fmt.Fprint(w, `<stat directory="/base/root"><file type="file" name="a"/><file type="dir" name="sub"/><resume start="/base/root/sub"/></stat>`)
+			case "version=1&action=dir&format=xml&max_entries=2&start=%2Fbase%2Froot%2Fsub":
+				fmt.Fprint(w, `<stat directory="/base/root"><file type="dir" name="skip"/></stat>`)
+			default:
+				t.Errorf("unexpected action %q", r.Header.Get("X-Akamai-ACS-Action"))
+			}
+		},
+	)
+	mux.HandleFunc("/base/root/sub",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<stat directory="/base/root/sub"><file type="file" name="b"/></stat>`)
+		},
+	)
+	mux.HandleFunc("/base/root/skip",
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("listed a skipped directory")
+		},
+	)
+}
+
+func TestWalk(t *testing.T) {
+	setup()
+	defer teardown()
+	handleTree(t)
+
+	expected := []string{"root", "root/a", "root/sub", "root/sub/b", "root/skip"}
+	for _, concurrency := range []int{0, 4} {
+		var visited []string
+		err := client.WalkWithOptions(context.Background(), "root", func(name string, file NSFile, err error) error {
+			if err != nil {
+				return err
+			}
+			visited = append(visited, name)
+			if name == "root/skip" {
+				return SkipDir
+			}
+			return nil
+		}, &WalkOptions{Concurrency: concurrency, PageSize: 2})
+		if err != nil {
+			t.Fatalf("walk error: %s", err)
+		}
+		want := expected
+		if concurrency > 1 {
+			sort.Strings(visited)
+			want = append([]string(nil), expected...)
+			sort.Strings(want)
+		}
+		if !reflect.DeepEqual(visited, want) {
+			t.Errorf("concurrency %d: got %v expected %v", concurrency, visited, want)
+		}
+	}
+}
+
+func TestWalkListError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/root",
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+		},
+	)
+	var calls int
+	err := client.Walk(context.Background(), "root", func(name string, file NSFile, err error) error {
+		calls++
+		return err
+	})
+	if err == nil || calls != 2 {
+		t.Errorf("got error %v after %d calls, expected an error after 2 calls", err, calls)
+	}
+}
@@ -0,0 +1,96 @@
+// netstorage project zip.go
+package netstorage
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// UploadZip uploads the zip archive read from r to name and has NetStorage
+// index it, so that its members can be served individually with
+// DownloadZipMember. If opts has no ContentType, application/zip is sent.
+func (client *NetstorageClient) UploadZip(ctx context.Context, name string, r io.Reader, opts *UploadOptions) error {
+	var o UploadOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.IndexZip = true
+	if o.ContentType == "" {
+		o.ContentType = "application/zip"
+	}
+	return client.UploadWithOptions(ctx, name, r, &o)
+}
+
+// UploadZipDir zips the regular files below localDir while uploading the
+// archive like UploadZip. Members are named by their path relative to
+// localDir and keep their modification times. As the archive is not
+// seekable, the upload is not retried unless opts.ComputeChecksums is set.
+func (client *NetstorageClient) UploadZipDir(ctx context.Context, name, localDir string, opts *UploadOptions) error {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := writeZip(pw, localDir)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+	err := client.UploadZip(ctx, name, pr, opts)
+	// stops the writer if the upload ended early
+	pr.Close()
+	if zerr := <-done; zerr != nil && !errors.Is(zerr, io.ErrClosedPipe) {
+		return zerr
+	}
+	return err
+}
+
+// writeZip writes a zip archive of the regular files below dir to w.
+func writeZip(w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+		member, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(member, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// DownloadZipMember streams the member of an archive uploaded with
+// UploadZip. NetStorage serves members of indexed archives below the path
+// of the archive, e.g. "site.zip/css/main.css". The caller must close the
+// returned reader.
+func (client *NetstorageClient) DownloadZipMember(ctx context.Context, archive, member string) (io.ReadCloser, *DownloadInfo, error) {
+	return client.DownloadStream(ctx, path.Join(archive, path.Clean("/"+member)))
+}
@@ -0,0 +1,93 @@
+// netstorage project zip_test.go
+package netstorage
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadZipDir(t *testing.T) {
+	setup()
+	defer teardown()
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), 0755)
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>"), 0644)
+	os.WriteFile(filepath.Join(dir, "css", "main.css"), []byte("body {}"), 0644)
+	mtime := time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC)
+	os.Chtimes(filepath.Join(dir, "index.html"), mtime, mtime)
+
+	mux.HandleFunc("/base/site.zip",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkMethod(t, r, "PUT")
+			checkAction(t, r, "version=1&action=upload&index-zip=1")
+			if got := r.Header.Get("Content-Type"); got != "application/zip" {
+				t.Errorf("Content-Type: got %q", got)
+			}
+			data, _ := io.ReadAll(r.Body)
+			archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("not a zip archive: %s", err)
+			}
+			var names []string
+			for _, f := range archive.File {
+				names = append(names, f.Name)
+				if f.Name == "index.html" && !f.Modified.Equal(mtime) {
+					t.Errorf("index.html modified %s, expected %s", f.Modified, mtime)
+				}
+			}
+			sort.Strings(names)
+			if strings.Join(names, ",") != "css/main.css,index.html" {
+				t.Errorf("members: %v", names)
+			}
+		},
+	)
+	if err := client.UploadZipDir(context.Background(), "site.zip", dir, nil); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+}
+
+func TestUploadZipDirMissing(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/site.zip",
+		func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+		},
+	)
+	err := client.UploadZipDir(context.Background(), "site.zip", filepath.Join(t.TempDir(), "missing"), nil)
+	if !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestDownloadZipMember(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/base/site.zip/css/main.css",
+		func(w http.ResponseWriter, r *http.Request) {
+			checkMethod(t, r, "GET")
+			checkAction(t, r, "version=1&action=download")
+			io.WriteString(w, "body {}")
+		},
+	)
+	body, _, err := client.DownloadZipMember(context.Background(), "site.zip", "/css/main.css")
+	if err != nil {
+		t.Fatalf("API error: %s", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "body {}" {
+		t.Errorf("got %q", data)
+	}
+}
@@ -3,6 +3,7 @@ package pulp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/Azure/azure-sdk-for-go/core/http"
@@ -10,6 +11,7 @@ import (
 	//"log"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	//"strconv"
 )
 
@@ -51,8 +53,9 @@ type pulpResponse struct {
 type Client struct {
 	Endpoint string
 	Cert     Certificate
-	UserName string //credentials to use
-	Password string //if certificate auth is not being used
+	UserName string       //credentials to use
+	Password string       //if certificate auth is not being used
+	Logger   *slog.Logger //requests are logged here at debug level, nil disables logging
 }
 
 type Certificate struct {
@@ -152,7 +155,7 @@ func (client *Client) ListRepositories() (Repositories, error) {
 	var pulpresponse *pulpResponse
 	var err error
 	var repository Repositories
-	if pulpresponse, err = execute("GET", "/pulp/api/v2/repositories/", nil, client.Endpoint, client.UserName, client.Password); err != nil {
+	if pulpresponse, err = execute("GET", "/pulp/api/v2/repositories/", nil, client.Endpoint, client.UserName, client.Password, client.Logger); err != nil {
 		return nil, err
 	}
 
@@ -168,9 +171,8 @@ func (client *Client) Authenticate() error {
 
 	var cert Certificate
 	var pulpresponse *pulpResponse
-	fmt.Println("endpoint: " + client.Endpoint)
 	var err error
-	if pulpresponse, err = execute("POST", "/pulp/api/v2/actions/login/", nil, client.Endpoint, client.UserName, client.Password); err != nil {
+	if pulpresponse, err = execute("POST", "/pulp/api/v2/actions/login/", nil, client.Endpoint, client.UserName, client.Password, client.Logger); err != nil {
 		return err
 	}
 
@@ -187,7 +189,7 @@ func (client *Client) GetRepository(repositoryName string) (RepositoryDetails, e
 	var err error
 	var repository RepositoryDetails
 
-	if response, err = execute("GET", "/pulp/api/v2/repositories/"+repositoryName+"/", nil, client.Endpoint, client.UserName, client.Password); err != nil {
+	if response, err = execute("GET", "/pulp/api/v2/repositories/"+repositoryName+"/", nil, client.Endpoint, client.UserName, client.Password, client.Logger); err != nil {
 		return repository, err
 	}
 
@@ -207,7 +209,7 @@ func (client *Client) CreateRepository(repodetails RepositoryDetails) (Repositor
 		return repositoryResponse, marshalerr
 	}
 
-	if response, err = execute("POST", "/pulp/api/v2/repositories/", jsondata, client.Endpoint, client.UserName, client.Password); err != nil {
+	if response, err = execute("POST", "/pulp/api/v2/repositories/", jsondata, client.Endpoint, client.UserName, client.Password, client.Logger); err != nil {
 		return repositoryResponse, err
 	}
 
@@ -223,7 +225,7 @@ func (client *Client) ListUploadRequests() (UploadRequests, error) {
 	var pulpresponse *pulpResponse
 	var err error
 	var uploadRequests UploadRequests
-	if pulpresponse, err = execute("GET", "/pulp/api/v2/content/uploads/", nil, client.Endpoint, client.UserName, client.Password); err != nil {
+	if pulpresponse, err = execute("GET", "/pulp/api/v2/content/uploads/", nil, client.Endpoint, client.UserName, client.Password, client.Logger); err != nil {
 		return uploadRequests, err
 	}
 
@@ -239,7 +241,7 @@ func (client *Client) CreateUploadRequest() (UploadRequest, error) {
 	var pulpresponse *pulpResponse
 	var err error
 	var uploadRequest UploadRequest
-	if pulpresponse, err = execute("POST", "/pulp/api/v2/content/uploads/", nil, client.Endpoint, client.UserName, client.Password); err != nil {
+	if pulpresponse, err = execute("POST", "/pulp/api/v2/content/uploads/", nil, client.Endpoint, client.UserName, client.Password, client.Logger); err != nil {
 		return uploadRequest, err
 	}
 
@@ -261,7 +263,10 @@ func errorFromJson(body []byte, code int) (*ErrorResponse, error) {
 	return &responseError, nil
 }
 
-func execute(verb, url string, content []byte, endPoint, userName, password string) (*pulpResponse, error) {
+func execute(verb, url string, content []byte, endPoint, userName, password string, logger *slog.Logger) (*pulpResponse, error) {
+	if logger == nil {
+		logger = discardLogger
+	}
 
 	transport := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
@@ -275,12 +280,16 @@ func execute(verb, url string, content []byte, endPoint, userName, password stri
 
 	request.SetBasicAuth(userName, password)
 
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		logger.Debug("pulp request", "method", verb, "url", endPoint+url, "content_length", len(content), headerAttr(request.Header))
+	}
 	response, err := defaultClient.Do(request)
 
-	fmt.Println("Creating repo with: ", string(content[:]))
 	if err != nil {
+		logger.Debug("pulp request failed", "method", verb, "url", endPoint+url, "error", err)
 		return nil, err
 	}
+	logger.Debug("pulp response", "method", verb, "url", endPoint+url, "status", response.StatusCode)
 
 	defer response.Body.Close()
 
@@ -1,12 +1,14 @@
-//
 package pulp
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -168,6 +170,33 @@ func TestCreateUploadRequest(t *testing.T) {
 	}
 }
 
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	setup()
+	defer teardown()
+	client.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client.Cert = Certificate{PkiCertificate: "pkiCert", PkiKey: "secretkey"}
+
+	mux.HandleFunc("/pulp/api/v2/content/uploads/",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"upload_ids": []}`)
+		},
+	)
+	if _, err := client.ListUploadRequests(); err != nil {
+		t.Errorf("API error: %s", err)
+	}
+	client.Logger.Debug("client", "client", client)
+	out := buf.String()
+	for _, want := range []string{"pulp request", "pulp response", "status=200", "Authorization=REDACTED", "password=REDACTED", "key=REDACTED"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "secretkey") || strings.Contains(out, "dGVzdDp0ZXN0") {
+		t.Errorf("log contains credentials:\n%s", out)
+	}
+}
+
 func checkMethod(t *testing.T, r *http.Request, want string) {
 	if got := r.Method; got != want {
 		t.Errorf("Request method: %v, want %v", got, want)
@@ -0,0 +1,71 @@
+// pulp project log.go
+package pulp
+
+import (
+	"context"
+	"log/slog"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// Headers whose values are never logged.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+}
+
+var discardLogger = slog.New(discardHandler{})
+
+// discardHandler is an slog.Handler that is never enabled.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// headerAttr groups request headers for logging, redacting credentials.
+func headerAttr(header map[string][]string) slog.Attr {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	attrs := make([]any, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if redactedHeaders[textproto.CanonicalMIMEHeaderKey(name)] {
+			value = "REDACTED"
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// LogValue keeps the private key out of logs.
+func (cert Certificate) LogValue() slog.Value {
+	key := ""
+	if cert.PkiKey != "" {
+		key = "REDACTED"
+	}
+	return slog.GroupValue(
+		slog.String("certificate", cert.PkiCertificate),
+		slog.String("key", key),
+	)
+}
+
+// LogValue keeps the password and private key out of logs.
+func (client *Client) LogValue() slog.Value {
+	password := ""
+	if client.Password != "" {
+		password = "REDACTED"
+	}
+	return slog.GroupValue(
+		slog.String("endpoint", client.Endpoint),
+		slog.String("user", client.UserName),
+		slog.String("password", password),
+		slog.Any("cert", client.Cert),
+	)
+}